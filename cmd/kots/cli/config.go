@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	kotsscheme "github.com/replicatedhq/kots/kotskinds/client/kotsclientset/scheme"
+	kotsconfig "github.com/replicatedhq/kots/pkg/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"k8s.io/apimachinery/pkg/runtime/serializer/json"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+func ConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Work with the config values of an application",
+		Long:  ``,
+	}
+
+	cmd.AddCommand(ConfigDecryptCmd())
+
+	return cmd
+}
+
+func ConfigDecryptCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           "decrypt [app dir]",
+		Short:         "Print the config values with the encrypted values decrypted",
+		Long:          `Print the config values of an application that was pulled to app dir, with the password values decrypted using the encryption key of the installation.`,
+		SilenceUsage:  true,
+		SilenceErrors: false,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				cmd.Help()
+				os.Exit(1)
+			}
+
+			configValues, err := kotsconfig.ReadDecryptedConfigValues(ExpandDir(args[0]))
+			if err != nil {
+				return errors.Cause(err)
+			}
+
+			kotsscheme.AddToScheme(scheme.Scheme)
+			s := json.NewYAMLSerializer(json.DefaultMetaFactory, scheme.Scheme, scheme.Scheme)
+			if err := s.Encode(configValues, os.Stdout); err != nil {
+				return errors.Wrap(err, "failed to encode config values")
+			}
+
+			return nil
+		},
+	}
+
+	return cmd
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/replicatedhq/kots/pkg/logger"
 	"github.com/replicatedhq/kots/pkg/pull"
 	"github.com/replicatedhq/kots/pkg/upload"
+	"github.com/replicatedhq/kots/pkg/upstream"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -55,6 +56,46 @@ func InstallCmd() *cobra.Command {
 				LicenseFile:         ExpandDir(v.GetString("license-file")),
 				ExcludeAdminConsole: true,
 				HelmOptions:         v.GetStringSlice("set"),
+				HelmValuesFiles:     ExpandDirs(v.GetStringSlice("values")),
+				KubeVersion:         v.GetString("kube-version"),
+				APIVersions:         v.GetStringSlice("api-versions"),
+				ValidateConfig:      !v.GetBool("skip-config-validation"),
+				Strict:              v.GetBool("strict"),
+				HelmRepoOptions: upstream.HelmRepoOptions{
+					Username:              v.GetString("repo-username"),
+					Password:              v.GetString("repo-password"),
+					CertFile:              ExpandDir(v.GetString("repo-cert-file")),
+					KeyFile:               ExpandDir(v.GetString("repo-key-file")),
+					CAFile:                ExpandDir(v.GetString("repo-ca-file")),
+					InsecureSkipTLSVerify: v.GetBool("repo-insecure-skip-tls-verify"),
+				},
+				HelmCacheOptions: upstream.HelmCacheOptions{
+					Dir:      ExpandDir(v.GetString("helm-cache-dir")),
+					IndexTTL: v.GetDuration("helm-index-ttl"),
+					Offline:  v.GetBool("offline"),
+				},
+			}
+
+			// render helm charts for the cluster that they are being installed to
+			if pullOptions.KubeVersion == "" || len(pullOptions.APIVersions) == 0 {
+				if _, err := os.Stat(v.GetString("kubeconfig")); err == nil {
+					kubeVersion, apiVersions, err := k8sutil.GetClusterCapabilities(v.GetString("kubeconfig"))
+					if err != nil {
+						return err
+					}
+
+					if pullOptions.KubeVersion == "" {
+						pullOptions.KubeVersion = kubeVersion
+					}
+					if len(pullOptions.APIVersions) == 0 {
+						pullOptions.APIVersions = apiVersions
+					}
+				}
+			}
+
+			// templates can read the cluster that the application is being installed to
+			if _, err := os.Stat(v.GetString("kubeconfig")); err == nil {
+				pullOptions.Kubeconfig = v.GetString("kubeconfig")
 			}
 
 			canPull, err := pull.CanPullUpstream(args[0], pullOptions)
@@ -156,7 +197,21 @@ func InstallCmd() *cobra.Command {
 	cmd.Flags().String("license-file", "", "path to a license file to use when download a replicated app")
 
 	cmd.Flags().String("repo", "", "repo uri to use when installing a helm chart")
+	cmd.Flags().String("repo-username", "", "username to use when downloading from a private helm repo (can also be set with KOTS_REPO_USERNAME)")
+	cmd.Flags().String("repo-password", "", "password to use when downloading from a private helm repo (can also be set with KOTS_REPO_PASSWORD)")
+	cmd.Flags().String("repo-cert-file", "", "client certificate to use when downloading from a helm repo that requires mutual tls")
+	cmd.Flags().String("repo-key-file", "", "client key to use when downloading from a helm repo that requires mutual tls")
+	cmd.Flags().String("repo-ca-file", "", "ca bundle to use to verify the helm repo certificate")
+	cmd.Flags().Bool("repo-insecure-skip-tls-verify", false, "set to true to skip verifying the helm repo certificate")
+	cmd.Flags().String("helm-cache-dir", filepath.Join(homeDir(), ".kots", "cache", "helm"), "the directory to cache helm repo indexes and charts in, set to empty to disable the cache")
+	cmd.Flags().Duration("helm-index-ttl", upstream.DefaultHelmIndexTTL, "how long a cached helm repo index is used before it's downloaded again")
+	cmd.Flags().Bool("offline", false, "set to true to only use cached helm repo indexes and charts")
 	cmd.Flags().StringSlice("set", []string{}, "values to pass to helm when running helm template")
+	cmd.Flags().StringSliceP("values", "f", []string{}, "values files to pass to helm when running helm template, merged in order beneath any --set values")
+	cmd.Flags().String("kube-version", "", "the kubernetes version to render helm charts for (discovered from the cluster when not set)")
+	cmd.Flags().StringSlice("api-versions", []string{}, "additional api versions to make available to helm charts (discovered from the cluster when not set)")
+	cmd.Flags().Bool("skip-config-validation", false, "set to true to render even when config values are missing or invalid")
+	cmd.Flags().Bool("strict", false, "set to true to fail when templates refer to undefined config items or have values that can't be converted")
 
 	return cmd
 }
@@ -1,11 +1,16 @@
 package cli
 
 import (
+	"fmt"
 	"os"
 	"path"
+	"path/filepath"
 
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/base"
 	"github.com/replicatedhq/kots/pkg/logger"
 	"github.com/replicatedhq/kots/pkg/pull"
+	"github.com/replicatedhq/kots/pkg/upstream"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -43,13 +48,51 @@ func PullCmd() *cobra.Command {
 				SharedPassword:      v.GetString("shared-password"),
 				CreateAppDir:        true,
 				HelmOptions:         v.GetStringSlice("set"),
+				HelmValuesFiles:     ExpandDirs(v.GetStringSlice("values")),
+				KubeVersion:         v.GetString("kube-version"),
+				APIVersions:         v.GetStringSlice("api-versions"),
+				ValidateConfig:      !v.GetBool("skip-config-validation"),
+				Strict:              v.GetBool("strict"),
+				RotateRandomStrings: v.GetBool("rotate-random-strings"),
+				Force:               v.GetBool("force"),
+				Kubeconfig:          ExpandDir(v.GetString("kubeconfig")),
+				ClusterInfoFile:     ExpandDir(v.GetString("cluster-info-file")),
 				RewriteImages:       v.GetBool("rewrite-images"),
+				HelmRepoOptions: upstream.HelmRepoOptions{
+					Username:              v.GetString("repo-username"),
+					Password:              v.GetString("repo-password"),
+					CertFile:              ExpandDir(v.GetString("repo-cert-file")),
+					KeyFile:               ExpandDir(v.GetString("repo-key-file")),
+					CAFile:                ExpandDir(v.GetString("repo-ca-file")),
+					InsecureSkipTLSVerify: v.GetBool("repo-insecure-skip-tls-verify"),
+				},
+				HelmCacheOptions: upstream.HelmCacheOptions{
+					Dir:      ExpandDir(v.GetString("helm-cache-dir")),
+					IndexTTL: v.GetDuration("helm-index-ttl"),
+					Offline:  v.GetBool("offline"),
+				},
 				RewriteImageOptions: pull.RewriteImageOptions{
 					Host:      v.GetString("registry-endpoint"),
 					Namespace: v.GetString("image-namespace"),
 				},
 			}
 
+			if v.GetBool("diff") && !v.GetBool("dry-run") {
+				return errors.New("--diff can only be used with --dry-run")
+			}
+
+			if v.GetBool("dry-run") {
+				pullOptions.Silent = true
+				changed, err := dryRunPull(args[0], pullOptions, v.GetBool("diff"))
+				if err != nil {
+					return err
+				}
+				if changed {
+					os.Exit(2)
+				}
+				return nil
+			}
+
 			renderDir, err := pull.Pull(args[0], pullOptions)
 			if err != nil {
 				return err
@@ -71,7 +114,27 @@ func PullCmd() *cobra.Command {
 	}
 
 	cmd.Flags().StringSlice("set", []string{}, "values to pass to helm when running helm template")
+	cmd.Flags().StringSliceP("values", "f", []string{}, "values files to pass to helm when running helm template, merged in order beneath any --set values")
+	cmd.Flags().String("kube-version", "", fmt.Sprintf("the kubernetes version to render helm charts for (defaults to %s)", base.DefaultKubeVersion))
+	cmd.Flags().StringSlice("api-versions", []string{}, "additional api versions to make available to helm charts")
+	cmd.Flags().String("kubeconfig", "", "the kubeconfig of the cluster that templates can read, such as the kubernetes version and secrets")
+	cmd.Flags().String("cluster-info-file", "", "a yaml file that describes the cluster, to use instead of a kubeconfig when rendering offline")
+	cmd.Flags().Bool("skip-config-validation", false, "set to true to render even when config values are missing or invalid")
+	cmd.Flags().Bool("strict", false, "set to true to fail when templates refer to undefined config items or have values that can't be converted")
+	cmd.Flags().Bool("dry-run", false, "set to true to pull into a temp dir and list the resources that would change, without changing the app on disk. exits with status 2 when resources would change")
+	cmd.Flags().Bool("diff", false, "set to true with --dry-run to print a diff of each resource that would change")
+	cmd.Flags().Bool("force", false, "set to true to discard local changes to the base instead of merging them with the upstream")
+	cmd.Flags().Bool("rotate-random-strings", false, "set to true to generate new values for RandomString instead of keeping the values from the previous pull")
 	cmd.Flags().String("repo", "", "repo uri to use when downloading a helm chart")
+	cmd.Flags().String("repo-username", "", "username to use when downloading from a private helm repo (can also be set with KOTS_REPO_USERNAME)")
+	cmd.Flags().String("repo-password", "", "password to use when downloading from a private helm repo (can also be set with KOTS_REPO_PASSWORD)")
+	cmd.Flags().String("repo-cert-file", "", "client certificate to use when downloading from a helm repo that requires mutual tls")
+	cmd.Flags().String("repo-key-file", "", "client key to use when downloading from a helm repo that requires mutual tls")
+	cmd.Flags().String("repo-ca-file", "", "ca bundle to use to verify the helm repo certificate")
+	cmd.Flags().Bool("repo-insecure-skip-tls-verify", false, "set to true to skip verifying the helm repo certificate")
+	cmd.Flags().String("helm-cache-dir", filepath.Join(homeDir(), ".kots", "cache", "helm"), "the directory to cache helm repo indexes and charts in, set to empty to disable the cache")
+	cmd.Flags().Duration("helm-index-ttl", upstream.DefaultHelmIndexTTL, "how long a cached helm repo index is used before it's downloaded again")
+	cmd.Flags().Bool("offline", false, "set to true to only use cached helm repo indexes and charts")
 	cmd.Flags().String("rootdir", homeDir(), "root directory that will be used to write the yaml to")
 	cmd.Flags().String("namespace", "default", "namespace to render the upstream to in the base")
 	cmd.Flags().StringSlice("downstream", []string{}, "the list of any downstreams to create/update")
@@ -86,3 +149,33 @@ func PullCmd() *cobra.Command {
 
 	return cmd
 }
+
+// dryRunPull pulls the app into a temp dir, and prints the resources that would change,
+// or their diffs. It returns whether there were changes
+func dryRunPull(upstreamURI string, pullOptions pull.PullOptions, printDiff bool) (bool, error) {
+	dryRun, err := pull.DryRunPull(upstreamURI, pullOptions)
+	if err != nil {
+		return false, err
+	}
+	defer dryRun.Remove()
+
+	kustomizationDiffs, err := dryRun.Diff()
+	if err != nil {
+		return false, errors.Wrap(err, "failed to diff dry run")
+	}
+
+	changed := false
+	for _, kustomizationDiff := range kustomizationDiffs {
+		for _, resourceDiff := range kustomizationDiff.Resources {
+			changed = true
+			if printDiff {
+				fmt.Printf("# %s: %s %s\n", kustomizationDiff.Kustomization, resourceDiff.ID, resourceDiff.Change)
+				fmt.Print(resourceDiff.Diff)
+			} else {
+				fmt.Printf("%s: %s %s\n", kustomizationDiff.Kustomization, resourceDiff.ID, resourceDiff.Change)
+			}
+		}
+	}
+
+	return changed, nil
+}
@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/k8sutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func RenderCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           "render [app dir]",
+		Short:         "Build the final manifests of an application with kustomize",
+		Long:          `Build the kustomization of a downstream of an application that was pulled to app dir, or of the midstream if there is no downstream, and print the manifests as a single yaml stream or write each to its own file.`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+
+			if len(args) == 0 {
+				cmd.Help()
+				os.Exit(1)
+			}
+
+			overlayDir := filepath.Join(ExpandDir(args[0]), "overlays", "midstream")
+			if downstream := v.GetString("downstream"); downstream != "" {
+				overlayDir = filepath.Join(ExpandDir(args[0]), "overlays", "downstreams", downstream)
+			}
+			if _, err := os.Stat(filepath.Join(overlayDir, "kustomization.yaml")); err != nil {
+				return errors.Errorf("no kustomization found in %s", overlayDir)
+			}
+
+			content, err := k8sutil.BuildKustomization(overlayDir)
+			if err != nil {
+				return errors.Wrap(err, "failed to build kustomization")
+			}
+
+			outputDir := v.GetString("output-dir")
+			if outputDir == "" {
+				_, err := os.Stdout.Write(content)
+				return err
+			}
+
+			resources, err := k8sutil.ParseResources(content)
+			if err != nil {
+				return errors.Wrap(err, "failed to parse resources")
+			}
+			if err := k8sutil.WriteResources(ExpandDir(outputDir), resources); err != nil {
+				return errors.Wrap(err, "failed to write resources")
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("downstream", "", "the downstream to render. the midstream is rendered if this is not set")
+	cmd.Flags().String("output-dir", "", "write each manifest to its own file in this dir, instead of printing them as a single yaml stream")
+
+	return cmd
+}
@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/upstream"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func RepoCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "repo",
+		Short: "Manage the helm repos that can be referenced by name in helm:// uris",
+		Long:  `Manage the helm repos that can be referenced by name in helm:// uris`,
+	}
+
+	cmd.AddCommand(RepoAddCmd())
+	cmd.AddCommand(RepoListCmd())
+	cmd.AddCommand(RepoRemoveCmd())
+
+	return cmd
+}
+
+func RepoAddCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           "add [name] [uri]",
+		Short:         "Add a helm repo",
+		Long:          `Add a helm repo, so that charts in it can be pulled with helm://[name]/[chart]`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+
+			if len(args) != 2 {
+				cmd.Help()
+				os.Exit(1)
+			}
+
+			filename, helmRepos, err := loadHelmRepos()
+			if err != nil {
+				return err
+			}
+
+			helmRepo := upstream.HelmRepo{
+				Name:                  args[0],
+				URI:                   args[1],
+				Username:              v.GetString("username"),
+				Password:              v.GetString("password"),
+				CertFile:              ExpandDir(v.GetString("cert-file")),
+				KeyFile:               ExpandDir(v.GetString("key-file")),
+				CAFile:                ExpandDir(v.GetString("ca-file")),
+				InsecureSkipTLSVerify: v.GetBool("insecure-skip-tls-verify"),
+			}
+			if err := helmRepos.Add(helmRepo, v.GetBool("force")); err != nil {
+				return errors.Wrap(err, "failed to add repo")
+			}
+
+			if err := helmRepos.Save(filename); err != nil {
+				return errors.Wrap(err, "failed to save repos")
+			}
+
+			fmt.Printf("%q has been added to your repositories\n", helmRepo.Name)
+			return nil
+		},
+	}
+
+	cmd.Flags().String("username", "", "username to use when downloading from the repo")
+	cmd.Flags().String("password", "", "password to use when downloading from the repo")
+	cmd.Flags().String("cert-file", "", "client certificate to use when the repo requires mutual tls")
+	cmd.Flags().String("key-file", "", "client key to use when the repo requires mutual tls")
+	cmd.Flags().String("ca-file", "", "ca bundle to use to verify the repo certificate")
+	cmd.Flags().Bool("insecure-skip-tls-verify", false, "set to true to skip verifying the repo certificate")
+	cmd.Flags().Bool("force", false, "set to true to replace an existing repo with the same name")
+
+	return cmd
+}
+
+func RepoListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           "list",
+		Short:         "List the helm repos",
+		Long:          `List the helm repos that have been added, and the built in repos`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, helmRepos, err := loadHelmRepos()
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tURI\tSOURCE")
+			for _, helmRepo := range helmRepos.Repos {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", helmRepo.Name, helmRepo.URI, "added")
+			}
+			for _, name := range sortedKnownRepoNames() {
+				if helmRepos.Get(name) != nil {
+					continue
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\n", name, upstream.KnownRepos[name], "built in")
+			}
+
+			return w.Flush()
+		},
+	}
+
+	return cmd
+}
+
+func RepoRemoveCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           "remove [name]",
+		Short:         "Remove a helm repo",
+		Long:          `Remove a helm repo that was added with "kots repo add"`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				cmd.Help()
+				os.Exit(1)
+			}
+
+			filename, helmRepos, err := loadHelmRepos()
+			if err != nil {
+				return err
+			}
+
+			if err := helmRepos.Remove(args[0]); err != nil {
+				return errors.Wrap(err, "failed to remove repo")
+			}
+
+			if err := helmRepos.Save(filename); err != nil {
+				return errors.Wrap(err, "failed to save repos")
+			}
+
+			fmt.Printf("%q has been removed from your repositories\n", args[0])
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func loadHelmRepos() (string, *upstream.HelmRepos, error) {
+	filename, err := upstream.HelmReposFilename()
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to get repos filename")
+	}
+
+	helmRepos, err := upstream.LoadHelmRepos(filename)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to load repos")
+	}
+
+	return filename, helmRepos, nil
+}
+
+func sortedKnownRepoNames() []string {
+	names := []string{}
+	for name := range upstream.KnownRepos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -26,6 +26,10 @@ func RootCmd() *cobra.Command {
 	cmd.AddCommand(UploadCmd())
 	cmd.AddCommand(DownloadCmd())
 	cmd.AddCommand(AdminConsoleCmd())
+	cmd.AddCommand(RepoCmd())
+	cmd.AddCommand(ConfigCmd())
+	cmd.AddCommand(TemplateCmd())
+	cmd.AddCommand(RenderCmd())
 	cmd.AddCommand(VersionCmd())
 
 	viper.BindPFlags(cmd.Flags())
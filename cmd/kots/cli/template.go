@@ -0,0 +1,201 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
+	kotsconfig "github.com/replicatedhq/kots/pkg/config"
+	"github.com/replicatedhq/kots/pkg/template"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func TemplateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "template",
+		Short: "Work with the templates of an application",
+		Long:  ``,
+	}
+
+	cmd.AddCommand(TemplateRenderCmd())
+	cmd.AddCommand(TemplateLintCmd())
+
+	return cmd
+}
+
+func TemplateRenderCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           "render [file]",
+		Short:         "Render the templates in a file",
+		Long:          `Render the repl templates in a file with the static functions and the config values, and print the result.`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+
+			if len(args) == 0 {
+				cmd.Help()
+				os.Exit(1)
+			}
+
+			if v.GetBool("strict") && v.GetString("config") == "" {
+				return errors.New("--strict requires --config")
+			}
+
+			filename := ExpandDir(args[0])
+			content, err := ioutil.ReadFile(filename)
+			if err != nil {
+				return errors.Wrap(err, "failed to read file")
+			}
+
+			templateContext := map[string]interface{}{}
+			if configValuesFile := v.GetString("config-values"); configValuesFile != "" {
+				configValuesContent, err := ioutil.ReadFile(ExpandDir(configValuesFile))
+				if err != nil {
+					return errors.Wrap(err, "failed to read config values")
+				}
+				configValues, err := kotsconfig.ParseConfigValues(configValuesContent)
+				if err != nil {
+					return errors.Wrap(err, "failed to parse config values")
+				}
+				templateContext = kotsconfig.TemplateContext(configValues)
+			}
+
+			var configGroups []kotsv1beta1.ConfigGroup
+			if configFile := v.GetString("config"); configFile != "" {
+				configContent, err := ioutil.ReadFile(ExpandDir(configFile))
+				if err != nil {
+					return errors.Wrap(err, "failed to read config")
+				}
+				config := kotsconfig.TryParseConfig(configContent)
+				if config == nil {
+					return errors.Errorf("%s is not a config object", configFile)
+				}
+				configGroups = config.Spec.Groups
+			}
+
+			builder := template.Builder{
+				Strict: v.GetBool("strict"),
+			}
+			builder.AddCtx(template.StaticCtx{})
+
+			configCtx, err := builder.NewConfigContext(configGroups, templateContext)
+			if err != nil {
+				return errors.Wrap(err, "failed to create config context")
+			}
+			builder.AddCtx(configCtx)
+
+			rendered, err := builder.RenderTemplate(filepath.Base(filename), string(content))
+			if err != nil {
+				return err
+			}
+
+			fmt.Print(rendered)
+			return nil
+		},
+	}
+
+	cmd.Flags().String("config-values", "", "path to a ConfigValues file with the values of the config items")
+	cmd.Flags().String("config", "", "path to the Config of the application, used for the defaults of the config items")
+	cmd.Flags().Bool("strict", false, "fail when a template refers to a config item that is not in the config, or a value cannot be parsed")
+
+	return cmd
+}
+
+func TemplateLintCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           "lint [dir]",
+		Short:         "Check the templates of an application for errors",
+		Long:          `Check every repl template in the files in dir for syntax errors, unknown functions, and config items that are not in the Config of the application. Exits with a non-zero status when there are problems.`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				cmd.Help()
+				os.Exit(1)
+			}
+
+			lintErrors, err := lintDir(ExpandDir(args[0]))
+			if err != nil {
+				return err
+			}
+
+			for _, lintError := range lintErrors {
+				fmt.Println(lintError.Error())
+			}
+
+			if len(lintErrors) > 0 {
+				return errors.Errorf("found %d problem(s)", len(lintErrors))
+			}
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// lintDir lints the templates of all of the files in dir, checking the config items
+// against the Config in dir if there is one
+func lintDir(dir string) ([]template.TemplateError, error) {
+	files := map[string][]byte{}
+	filenames := []string{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read %s", path)
+		}
+
+		files[path] = content
+		filenames = append(filenames, path)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to walk dir")
+	}
+
+	var configGroups []kotsv1beta1.ConfigGroup
+	for _, filename := range filenames {
+		if config := kotsconfig.TryParseConfig(files[filename]); config != nil {
+			configGroups = config.Spec.Groups
+			if configGroups == nil {
+				configGroups = []kotsv1beta1.ConfigGroup{}
+			}
+		}
+	}
+
+	lintErrors := []template.TemplateError{}
+	for _, filename := range filenames {
+		content := files[filename]
+		if !bytes.Contains(content, []byte("{{repl")) {
+			continue
+		}
+
+		relPath, err := filepath.Rel(dir, filename)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get relative path")
+		}
+
+		lintErrors = append(lintErrors, template.Lint(relPath, string(content), configGroups)...)
+	}
+
+	return lintErrors, nil
+}
@@ -1,9 +1,10 @@
 package cli
 
 import (
-	"os"
 	"path"
 	"strings"
+
+	"github.com/replicatedhq/kots/pkg/util"
 )
 
 func ExpandDir(input string) string {
@@ -14,9 +15,15 @@ func ExpandDir(input string) string {
 	return path.Join(homeDir(), input[1:])
 }
 
-func homeDir() string {
-	if h := os.Getenv("HOME"); h != "" {
-		return h
+func ExpandDirs(inputs []string) []string {
+	expanded := []string{}
+	for _, input := range inputs {
+		expanded = append(expanded, ExpandDir(input))
 	}
-	return os.Getenv("USERPROFILE")
+
+	return expanded
+}
+
+func homeDir() string {
+	return util.HomeDir()
 }
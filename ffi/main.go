@@ -81,12 +81,15 @@ func UpdateCheck(socket, fromArchivePath string) {
 		}
 		license := obj.(*kotsv1beta1.License)
 
+		// the archive doesn't keep a pristine copy of the base to merge local changes
+		// with, so the base is replaced with the update
 		pullOptions := pull.PullOptions{
 			LicenseFile:         expectedLicenseFile,
 			RootDir:             tmpRoot,
 			ExcludeKotsKinds:    true,
 			ExcludeAdminConsole: true,
 			CreateAppDir:        false,
+			Force:               true,
 		}
 
 		if _, err := pull.Pull(fmt.Sprintf("replicated://%s", license.Spec.AppSlug), pullOptions); err != nil {
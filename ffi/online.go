@@ -9,10 +9,8 @@ import (
 	"path"
 
 	"github.com/mholt/archiver"
-	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
-	kotsscheme "github.com/replicatedhq/kots/kotskinds/client/kotsclientset/scheme"
+	kotslicense "github.com/replicatedhq/kots/pkg/license"
 	"github.com/replicatedhq/kots/pkg/pull"
-	"k8s.io/client-go/kubernetes/scheme"
 )
 
 //export PullFromLicense
@@ -29,15 +27,12 @@ func PullFromLicense(socket string, licenseData string, downstream string, outpu
 			statusClient.end(ffiResult)
 		}()
 
-		kotsscheme.AddToScheme(scheme.Scheme)
-		decode := scheme.Codecs.UniversalDeserializer().Decode
-		obj, _, err := decode([]byte(licenseData), nil, nil)
+		license, err := kotslicense.ParseLicense([]byte(licenseData))
 		if err != nil {
-			fmt.Printf("failed to decode license data: %s\n", err.Error())
+			fmt.Printf("failed to parse license data: %s\n", err.Error())
 			ffiResult = NewFFIResult(1).WithError(err)
 			return
 		}
-		license := obj.(*kotsv1beta1.License)
 
 		licenseFile, err := ioutil.TempFile("", "kots")
 		if err != nil {
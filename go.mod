@@ -51,6 +51,7 @@ require (
 	github.com/mattn/go-shellwords v1.0.5 // indirect
 	github.com/mholt/archiver v3.1.1+incompatible
 	github.com/mistifyio/go-zfs v2.1.1+incompatible // indirect
+	github.com/modern-go/reflect2 v1.0.2 // indirect
 	github.com/mtrmac/gpgme v0.0.0-20170102180018-b2432428689c // indirect
 	github.com/nwaples/rardecode v1.0.0 // indirect
 	github.com/opencontainers/go-digest v1.0.0-rc1 // indirect
@@ -61,6 +62,7 @@ require (
 	github.com/otiai10/copy v1.0.2
 	github.com/pierrec/lz4 v2.2.6+incompatible // indirect
 	github.com/pkg/errors v0.8.1
+	github.com/pmezard/go-difflib v1.0.0
 	github.com/pquerna/ffjson v0.0.0-20181028064349-e517b90714f7 // indirect
 	github.com/sirupsen/logrus v1.4.2 // indirect
 	github.com/spf13/cobra v0.0.5
@@ -1,7 +1,6 @@
 package replicated
 
 import (
-	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
@@ -13,16 +12,10 @@ import (
 
 // GenerateTest will create a new replicated app fixture for integration tests
 func GenerateTest(name string, rawArchivePath string) error {
-	integrationLicenseData := fmt.Sprintf(`apiVersion: kots.io/v1beta1
-kind: License
-metadata:
-  name: integration
-spec:
-  licenseID: %s
-  appSlug: %s
-  endpoint: http://localhost:3000
-  signature: IA==
-`, name, name)
+	integrationLicenseData, err := generateTestLicense(name)
+	if err != nil {
+		return errors.Wrap(err, "failed to generate license")
+	}
 
 	replicatedAppArchive, err := generateReplicatedAppArchive(rawArchivePath)
 	if err != nil {
@@ -39,7 +32,7 @@ spec:
 		return errors.Wrap(err, "failed to create test root")
 	}
 
-	err = ioutil.WriteFile(path.Join(testRoot, "license.yaml"), []byte(integrationLicenseData), 0644)
+	err = ioutil.WriteFile(path.Join(testRoot, "license.yaml"), integrationLicenseData, 0644)
 	if err != nil {
 		return errors.Wrap(err, "failed to write license")
 	}
@@ -0,0 +1,24 @@
+//go:build testlicensekeys
+// +build testlicensekeys
+
+package replicated
+
+import (
+	"github.com/pkg/errors"
+	kotslicense "github.com/replicatedhq/kots/pkg/license"
+)
+
+// UseTestLicenseKey trusts licenses that are signed with the test license key
+func UseTestLicenseKey() error {
+	privateKey, err := parseTestLicenseKey()
+	if err != nil {
+		return errors.Wrap(err, "failed to parse test license key")
+	}
+
+	publicKey, err := encodePublicKey(&privateKey.PublicKey)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode public key")
+	}
+
+	return kotslicense.AddTestPublicKey(testGlobalKeyID, publicKey)
+}
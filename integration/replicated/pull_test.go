@@ -1,3 +1,6 @@
+//go:build testlicensekeys
+// +build testlicensekeys
+
 package replicated
 
 import (
@@ -21,6 +24,8 @@ type replicatedPullTest struct {
 const endpoint = "http://localhost:3000"
 
 func Test_PullReplicated(t *testing.T) {
+	require.NoError(t, UseTestLicenseKey())
+
 	tests := []replicatedPullTest{}
 
 	testDirs, err := ioutil.ReadDir("tests")
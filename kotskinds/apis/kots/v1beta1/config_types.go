@@ -28,24 +28,34 @@ type ConfigChildItem struct {
 	Value       string `son:"value,omitempty"`
 }
 
+type RegexValidator struct {
+	Pattern string `json:"pattern"`
+	Message string `json:"message,omitempty"`
+}
+
+type ConfigItemValidation struct {
+	Regex *RegexValidator `json:"regex,omitempty"`
+}
+
 type ConfigItem struct {
-	Name        string            `json:"name"`
-	Type        string            `json:"type"`
-	Title       string            `json:"title,omitempty"`
-	HelpText    string            `json:"help_text,omitempty"`
-	Recommended bool              `json:"recommended,omitempty"`
-	Default     string            `json:"default,omitempty"`
-	Value       string            `json:"value,omitempty"`
-	MultiValue  []string          `json:"multi_value,omitempty"`
-	ReadOnly    bool              `json:"readonly,omitempty"`
-	WriteOnce   bool              `json:"write_once,omitempty"`
-	When        string            `json:"when,omitempty"`
-	Multiple    bool              `json:"multiple,omitempty"`
-	Hidden      bool              `json:"hidden,omitempty"`
-	Position    int               `json:"-"`
-	Affix       string            `json:"affix,omitempty"`
-	Required    bool              `json:"required,omitempty"`
-	Items       []ConfigChildItem `json:"items,omitempty"`
+	Name        string                `json:"name"`
+	Type        string                `json:"type"`
+	Title       string                `json:"title,omitempty"`
+	HelpText    string                `json:"help_text,omitempty"`
+	Recommended bool                  `json:"recommended,omitempty"`
+	Default     string                `json:"default,omitempty"`
+	Value       string                `json:"value,omitempty"`
+	MultiValue  []string              `json:"multi_value,omitempty"`
+	ReadOnly    bool                  `json:"readonly,omitempty"`
+	WriteOnce   bool                  `json:"write_once,omitempty"`
+	When        string                `json:"when,omitempty"`
+	Multiple    bool                  `json:"multiple,omitempty"`
+	Hidden      bool                  `json:"hidden,omitempty"`
+	Position    int                   `json:"-"`
+	Affix       string                `json:"affix,omitempty"`
+	Required    bool                  `json:"required,omitempty"`
+	Items       []ConfigChildItem     `json:"items,omitempty"`
+	Validation  *ConfigItemValidation `json:"validation,omitempty"`
 	// Props       map[string]interface{} `json:"props,omitempty"`
 	// DefaultCmd  *ConfigItemCmd         `json:"default_cmd,omitempty"`
 	// ValueCmd    *ConfigItemCmd         `json:"value_cmd,omitempty"`
@@ -57,6 +67,9 @@ type ConfigGroup struct {
 	Title       string       `json:"title"`
 	Description string       `json:"description,omitempty"`
 	Items       []ConfigItem `json:"items,omitempty"`
+	// Repeatable groups can be filled in more than once, and every item in the group
+	// has one value for each time the group is repeated
+	Repeatable bool `json:"repeatable,omitempty"`
 }
 
 // ConfigSpec defines the desired state of ConfigSpec
@@ -23,6 +23,9 @@ import (
 // ConfigValuesSpec defines the desired state of ConfigValue
 type ConfigValuesSpec struct {
 	Values map[string]string `json:"values"`
+	// MultiValues are the values of items that have more than one value, either because
+	// the item allows multiple values or because its group is repeatable
+	MultiValues map[string][]string `json:"multiValues,omitempty"`
 }
 
 // ConfigValuesStatus defines the observed state of ConfigValues
@@ -25,6 +25,28 @@ type InstallationSpec struct {
 	UpdateCursor  string `json:"updateCursor,omitempty"`
 	VersionLabel  string `json:"versionLabel,omitempty"`
 	EncryptionKey string `json:"encryptionKey,omitempty"`
+
+	GeneratedValues *GeneratedValues `json:"generatedValues,omitempty"`
+}
+
+// GeneratedValues are the values that templates generated, kept so that they are the
+// same every time the application is rendered
+type GeneratedValues struct {
+	Certificates  map[string]GeneratedCertificate `json:"certificates,omitempty"`
+	KeyPairs      map[string]GeneratedKeyPair     `json:"keyPairs,omitempty"`
+	RandomStrings map[string]string               `json:"randomStrings,omitempty"`
+}
+
+// GeneratedCertificate is a PEM encoded certificate and its private key
+type GeneratedCertificate struct {
+	Cert string `json:"cert"`
+	Key  string `json:"key"`
+}
+
+// GeneratedKeyPair is a PEM encoded RSA key pair
+type GeneratedKeyPair struct {
+	PublicKey  string `json:"publicKey"`
+	PrivateKey string `json:"privateKey"`
 }
 
 // InstallationStatus defines the observed state of Installation
@@ -17,16 +17,98 @@ limitations under the License.
 package v1beta1
 
 import (
+	"encoding/json"
+	"strconv"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // LicenseSpec defines the desired state of LicenseSpec
 type LicenseSpec struct {
-	Signature         []byte `json:"signature"`
-	AppSlug           string `json:"appSlug"`
-	Endpoint          string `json:"endpoint,omitempty"`
-	LicenseID         string `json:"licenseID"`
-	IsAirgapSupported bool   `json:"isAirgapSupported,omitempty"`
+	Signature         []byte                      `json:"signature"`
+	AppSlug           string                      `json:"appSlug"`
+	Endpoint          string                      `json:"endpoint,omitempty"`
+	LicenseID         string                      `json:"licenseID"`
+	IsAirgapSupported bool                        `json:"isAirgapSupported,omitempty"`
+	Entitlements      map[string]EntitlementField `json:"entitlements,omitempty"`
+}
+
+// EntitlementField is a field of the license that the customer is entitled to, such as
+// the number of seats or whether a feature is enabled
+type EntitlementField struct {
+	Title       string           `json:"title,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Value       EntitlementValue `json:"value"`
+}
+
+type EntitlementValueType int
+
+const (
+	EntitlementValueTypeString EntitlementValueType = iota
+	EntitlementValueTypeInt
+	EntitlementValueTypeBool
+)
+
+// EntitlementValue is a string, int or bool value
+type EntitlementValue struct {
+	Type    EntitlementValueType `json:"-"`
+	StrVal  string               `json:"-"`
+	IntVal  int64                `json:"-"`
+	BoolVal bool                 `json:"-"`
+}
+
+// Value returns the value as its type
+func (v EntitlementValue) Value() interface{} {
+	switch v.Type {
+	case EntitlementValueTypeInt:
+		return v.IntVal
+	case EntitlementValueTypeBool:
+		return v.BoolVal
+	default:
+		return v.StrVal
+	}
+}
+
+func (v EntitlementValue) String() string {
+	switch v.Type {
+	case EntitlementValueTypeInt:
+		return strconv.FormatInt(v.IntVal, 10)
+	case EntitlementValueTypeBool:
+		return strconv.FormatBool(v.BoolVal)
+	default:
+		return v.StrVal
+	}
+}
+
+func (v EntitlementValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.Value())
+}
+
+func (v *EntitlementValue) UnmarshalJSON(b []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(b, &value); err != nil {
+		return err
+	}
+
+	switch value := value.(type) {
+	case bool:
+		*v = EntitlementValue{Type: EntitlementValueTypeBool, BoolVal: value}
+		return nil
+	case float64:
+		if value == float64(int64(value)) {
+			*v = EntitlementValue{Type: EntitlementValueTypeInt, IntVal: int64(value)}
+			return nil
+		}
+	case string:
+		*v = EntitlementValue{Type: EntitlementValueTypeString, StrVal: value}
+		return nil
+	case nil:
+		*v = EntitlementValue{}
+		return nil
+	}
+
+	*v = EntitlementValue{Type: EntitlementValueTypeString, StrVal: string(b)}
+	return nil
 }
 
 // LicenseStatus defines the observed state of License
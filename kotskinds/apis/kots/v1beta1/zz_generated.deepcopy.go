@@ -190,6 +190,11 @@ func (in *ConfigItem) DeepCopyInto(out *ConfigItem) {
 		*out = make([]ConfigChildItem, len(*in))
 		copy(*out, *in)
 	}
+	if in.Validation != nil {
+		in, out := &in.Validation, &out.Validation
+		*out = new(ConfigItemValidation)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigItem.
@@ -202,6 +207,26 @@ func (in *ConfigItem) DeepCopy() *ConfigItem {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigItemValidation) DeepCopyInto(out *ConfigItemValidation) {
+	*out = *in
+	if in.Regex != nil {
+		in, out := &in.Regex, &out.Regex
+		*out = new(RegexValidator)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigItemValidation.
+func (in *ConfigItemValidation) DeepCopy() *ConfigItemValidation {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigItemValidation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ConfigList) DeepCopyInto(out *ConfigList) {
 	*out = *in
@@ -340,6 +365,21 @@ func (in *ConfigValuesSpec) DeepCopyInto(out *ConfigValuesSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.MultiValues != nil {
+		in, out := &in.MultiValues, &out.MultiValues
+		*out = make(map[string][]string, len(*in))
+		for key, val := range *in {
+			var outVal []string
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = make([]string, len(*in))
+				copy(*out, *in)
+			}
+			(*out)[key] = outVal
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigValuesSpec.
@@ -367,12 +407,109 @@ func (in *ConfigValuesStatus) DeepCopy() *ConfigValuesStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EntitlementField) DeepCopyInto(out *EntitlementField) {
+	*out = *in
+	out.Value = in.Value
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EntitlementField.
+func (in *EntitlementField) DeepCopy() *EntitlementField {
+	if in == nil {
+		return nil
+	}
+	out := new(EntitlementField)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EntitlementValue) DeepCopyInto(out *EntitlementValue) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EntitlementValue.
+func (in *EntitlementValue) DeepCopy() *EntitlementValue {
+	if in == nil {
+		return nil
+	}
+	out := new(EntitlementValue)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GeneratedCertificate) DeepCopyInto(out *GeneratedCertificate) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GeneratedCertificate.
+func (in *GeneratedCertificate) DeepCopy() *GeneratedCertificate {
+	if in == nil {
+		return nil
+	}
+	out := new(GeneratedCertificate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GeneratedKeyPair) DeepCopyInto(out *GeneratedKeyPair) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GeneratedKeyPair.
+func (in *GeneratedKeyPair) DeepCopy() *GeneratedKeyPair {
+	if in == nil {
+		return nil
+	}
+	out := new(GeneratedKeyPair)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GeneratedValues) DeepCopyInto(out *GeneratedValues) {
+	*out = *in
+	if in.Certificates != nil {
+		in, out := &in.Certificates, &out.Certificates
+		*out = make(map[string]GeneratedCertificate, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.KeyPairs != nil {
+		in, out := &in.KeyPairs, &out.KeyPairs
+		*out = make(map[string]GeneratedKeyPair, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.RandomStrings != nil {
+		in, out := &in.RandomStrings, &out.RandomStrings
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GeneratedValues.
+func (in *GeneratedValues) DeepCopy() *GeneratedValues {
+	if in == nil {
+		return nil
+	}
+	out := new(GeneratedValues)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Installation) DeepCopyInto(out *Installation) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	out.Status = in.Status
 }
 
@@ -429,6 +566,11 @@ func (in *InstallationList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *InstallationSpec) DeepCopyInto(out *InstallationSpec) {
 	*out = *in
+	if in.GeneratedValues != nil {
+		in, out := &in.GeneratedValues, &out.GeneratedValues
+		*out = new(GeneratedValues)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstallationSpec.
@@ -523,6 +665,13 @@ func (in *LicenseSpec) DeepCopyInto(out *LicenseSpec) {
 		*out = make([]byte, len(*in))
 		copy(*out, *in)
 	}
+	if in.Entitlements != nil {
+		in, out := &in.Entitlements, &out.Entitlements
+		*out = make(map[string]EntitlementField, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LicenseSpec.
@@ -549,3 +698,18 @@ func (in *LicenseStatus) DeepCopy() *LicenseStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegexValidator) DeepCopyInto(out *RegexValidator) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegexValidator.
+func (in *RegexValidator) DeepCopy() *RegexValidator {
+	if in == nil {
+		return nil
+	}
+	out := new(RegexValidator)
+	in.DeepCopyInto(out)
+	return out
+}
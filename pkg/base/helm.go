@@ -1,22 +1,28 @@
 package base
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
 	"strings"
 
+	"github.com/Masterminds/semver"
 	"github.com/ghodss/yaml"
 	"github.com/pkg/errors"
 	"github.com/replicatedhq/kots/pkg/upstream"
-	"github.com/replicatedhq/kots/pkg/util"
 	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/engine"
 	"k8s.io/helm/pkg/proto/hapi/chart"
 	"k8s.io/helm/pkg/renderutil"
-	"k8s.io/helm/pkg/strvals"
 	"k8s.io/helm/pkg/timeconv"
+	tversion "k8s.io/helm/pkg/version"
 )
 
+// DefaultKubeVersion is the kubernetes version that charts are rendered for
+// when one isn't specified
+const DefaultKubeVersion = "1.16.0"
+
 func renderHelm(u *upstream.Upstream, renderOptions *RenderOptions) (*Base, error) {
 	chartPath, err := ioutil.TempDir("", "kots")
 	if err != nil {
@@ -24,7 +30,19 @@ func renderHelm(u *upstream.Upstream, renderOptions *RenderOptions) (*Base, erro
 	}
 	defer os.RemoveAll(chartPath)
 
+	vals := map[string]interface{}{}
 	for _, file := range u.Files {
+		// the userdata is not part of the chart, but it holds the values for the render.
+		// --set and --values were merged into values.yaml when the upstream was written
+		if strings.HasPrefix(file.Path, "userdata/") {
+			if file.Path == "userdata/values.yaml" {
+				if err := yaml.Unmarshal(file.Content, &vals); err != nil {
+					return nil, errors.Wrap(err, "failed to unmarshal helm values")
+				}
+			}
+			continue
+		}
+
 		p := path.Join(chartPath, file.Path)
 		d, _ := path.Split(p)
 		if _, err := os.Stat(d); err != nil {
@@ -42,12 +60,6 @@ func renderHelm(u *upstream.Upstream, renderOptions *RenderOptions) (*Base, erro
 		}
 	}
 
-	vals := map[string]interface{}{}
-	for _, value := range renderOptions.HelmOptions {
-		if err := strvals.ParseInto(value, vals); err != nil {
-			return nil, errors.Wrap(err, "failed to parse helm value")
-		}
-	}
 	marshalledVals, err := yaml.Marshal(vals)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to marshal helm values")
@@ -60,18 +72,20 @@ func renderHelm(u *upstream.Upstream, renderOptions *RenderOptions) (*Base, erro
 		return nil, errors.Wrap(err, "failed to load chart")
 	}
 
-	renderOpts := renderutil.Options{
-		ReleaseOptions: chartutil.ReleaseOptions{
-			Name:      u.Name,
-			IsInstall: true,
-			IsUpgrade: false,
-			Time:      timeconv.Now(),
-			Namespace: renderOptions.Namespace,
-		},
-		KubeVersion: "1.16.0",
+	releaseOptions := chartutil.ReleaseOptions{
+		Name:      u.Name,
+		IsInstall: true,
+		IsUpgrade: false,
+		Time:      timeconv.Now(),
+		Namespace: renderOptions.Namespace,
 	}
 
-	rendered, err := renderutil.Render(c, config, renderOpts)
+	caps, err := getHelmCapabilities(renderOptions.KubeVersion, renderOptions.APIVersions)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get capabilities")
+	}
+
+	rendered, err := renderHelmChart(c, config, releaseOptions, caps)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to render chart")
 	}
@@ -79,42 +93,104 @@ func renderHelm(u *upstream.Upstream, renderOptions *RenderOptions) (*Base, erro
 	baseFiles := []BaseFile{}
 	for k, v := range rendered {
 		baseFile := BaseFile{
-			Path:    k,
+			Path:    getHelmBasePath(k),
 			Content: []byte(v),
 		}
 
 		baseFiles = append(baseFiles, baseFile)
 	}
 
-	// remove any common prefix from all files
-	if len(baseFiles) > 0 {
-		firstFileDir, _ := path.Split(baseFiles[0].Path)
-		commonPrefix := strings.Split(firstFileDir, string(os.PathSeparator))
+	return &Base{
+		Files: baseFiles,
+	}, nil
+}
 
-		for _, file := range baseFiles {
-			d, _ := path.Split(file.Path)
-			dirs := strings.Split(d, string(os.PathSeparator))
+// renderHelmChart is the same as renderutil.Render, but it allows the api versions
+// in the capabilities to be set
+func renderHelmChart(c *chart.Chart, config *chart.Config, releaseOptions chartutil.ReleaseOptions, caps *chartutil.Capabilities) (map[string]string, error) {
+	if req, err := chartutil.LoadRequirements(c); err == nil {
+		if err := renderutil.CheckDependencies(c, req); err != nil {
+			return nil, errors.Wrap(err, "failed to check dependencies")
+		}
+	} else if err != chartutil.ErrRequirementsNotFound {
+		return nil, errors.Wrap(err, "failed to load requirements")
+	}
 
-			commonPrefix = util.CommonSlicePrefix(commonPrefix, dirs)
+	if err := chartutil.ProcessRequirementsEnabled(c, config); err != nil {
+		return nil, errors.Wrap(err, "failed to process requirements enabled")
+	}
+	if err := chartutil.ProcessRequirementsImportValues(c); err != nil {
+		return nil, errors.Wrap(err, "failed to process requirements import values")
+	}
 
-		}
+	vals, err := chartutil.ToRenderValuesCaps(c, config, releaseOptions, caps)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get render values")
+	}
 
-		cleanedBaseFiles := []BaseFile{}
-		for _, file := range baseFiles {
-			d, f := path.Split(file.Path)
-			d2 := strings.Split(d, string(os.PathSeparator))
+	return engine.New().Render(c, vals)
+}
 
-			cleanedBaseFile := file
-			d2 = d2[len(commonPrefix):]
-			cleanedBaseFile.Path = path.Join(path.Join(d2...), f)
+// getHelmCapabilities returns the capabilities for the chart to render with. The kube
+// version defaults to DefaultKubeVersion, and any api versions are added to the helm defaults
+func getHelmCapabilities(kubeVersion string, apiVersions []string) (*chartutil.Capabilities, error) {
+	if kubeVersion == "" {
+		kubeVersion = DefaultKubeVersion
+	}
 
-			cleanedBaseFiles = append(cleanedBaseFiles, cleanedBaseFile)
-		}
+	kv, err := semver.NewVersion(kubeVersion)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse kubernetes version %q", kubeVersion)
+	}
+
+	versionInfo := *chartutil.DefaultKubeVersion
+	versionInfo.Major = fmt.Sprint(kv.Major())
+	versionInfo.Minor = fmt.Sprint(kv.Minor())
+	versionInfo.GitVersion = fmt.Sprintf("v%d.%d.%d", kv.Major(), kv.Minor(), kv.Patch())
 
-		baseFiles = cleanedBaseFiles
+	versionSet := chartutil.DefaultVersionSet
+	if len(apiVersions) > 0 {
+		versionSet = chartutil.NewVersionSet(append([]string{"v1"}, apiVersions...)...)
 	}
 
-	return &Base{
-		Files: baseFiles,
+	return &chartutil.Capabilities{
+		APIVersions:   versionSet,
+		KubeVersion:   &versionInfo,
+		TillerVersion: tversion.GetVersionProto(),
 	}, nil
 }
+
+// getHelmBasePath maps the path of a rendered template to the path in the base. Templates
+// from the chart are written to the root of the base, and the templates from each subchart
+// are written to charts/<subchart>, so
+//
+//	mychart/templates/deployment.yaml -> deployment.yaml
+//	mychart/charts/redis/templates/service.yaml -> charts/redis/service.yaml
+func getHelmBasePath(renderedPath string) string {
+	parts := strings.Split(renderedPath, "/")
+	if len(parts) < 2 {
+		return renderedPath
+	}
+
+	// the first element is always the name of the chart
+	parts = parts[1:]
+
+	basePath := []string{}
+	for len(parts) > 0 {
+		if parts[0] == "templates" {
+			basePath = append(basePath, parts[1:]...)
+			break
+		}
+
+		if parts[0] == "charts" && len(parts) > 1 {
+			basePath = append(basePath, parts[0], parts[1])
+			parts = parts[2:]
+			continue
+		}
+
+		basePath = append(basePath, parts...)
+		break
+	}
+
+	return path.Join(basePath...)
+}
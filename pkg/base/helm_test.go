@@ -0,0 +1,171 @@
+package base
+
+import (
+	"testing"
+
+	"github.com/replicatedhq/kots/pkg/upstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_getHelmBasePath(t *testing.T) {
+	tests := []struct {
+		renderedPath string
+		expected     string
+	}{
+		{
+			renderedPath: "mychart/templates/deployment.yaml",
+			expected:     "deployment.yaml",
+		},
+		{
+			renderedPath: "mychart/templates/nested/deployment.yaml",
+			expected:     "nested/deployment.yaml",
+		},
+		{
+			renderedPath: "mychart/charts/redis/templates/service.yaml",
+			expected:     "charts/redis/service.yaml",
+		},
+		{
+			renderedPath: "mychart/charts/redis/charts/sentinel/templates/service.yaml",
+			expected:     "charts/redis/charts/sentinel/service.yaml",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.renderedPath, func(t *testing.T) {
+			assert.Equal(t, test.expected, getHelmBasePath(test.renderedPath))
+		})
+	}
+}
+
+func Test_renderHelmUmbrellaChart(t *testing.T) {
+	u := &upstream.Upstream{
+		Name: "umbrella",
+		Type: "helm",
+		Files: []upstream.UpstreamFile{
+			{
+				Path:    "Chart.yaml",
+				Content: []byte("apiVersion: v1\nname: umbrella\nversion: 0.1.0\n"),
+			},
+			{
+				Path:    "values.yaml",
+				Content: []byte("redis:\n  enabled: true\nmemcached:\n  enabled: false\n"),
+			},
+			{
+				Path: "requirements.yaml",
+				Content: []byte(`dependencies:
+- name: redis
+  version: 1.0.0
+  repository: https://example.com/charts
+  condition: redis.enabled
+- name: memcached
+  version: 1.0.0
+  repository: https://example.com/charts
+  condition: memcached.enabled
+`),
+			},
+			{
+				Path:    "templates/configmap.yaml",
+				Content: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: umbrella\n"),
+			},
+			{
+				Path:    "charts/redis/Chart.yaml",
+				Content: []byte("apiVersion: v1\nname: redis\nversion: 1.0.0\n"),
+			},
+			{
+				Path:    "charts/redis/templates/service.yaml",
+				Content: []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: redis\n"),
+			},
+			{
+				Path:    "charts/memcached/Chart.yaml",
+				Content: []byte("apiVersion: v1\nname: memcached\nversion: 1.0.0\n"),
+			},
+			{
+				Path:    "charts/memcached/templates/service.yaml",
+				Content: []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: memcached\n"),
+			},
+		},
+	}
+
+	renderedPaths := func(b *Base) []string {
+		paths := []string{}
+		for _, f := range b.Files {
+			paths = append(paths, f.Path)
+		}
+		return paths
+	}
+
+	t.Run("conditions from values", func(t *testing.T) {
+		b, err := renderHelm(u, &RenderOptions{Namespace: "default"})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"configmap.yaml", "charts/redis/service.yaml"}, renderedPaths(b))
+	})
+
+	t.Run("conditions overridden in the userdata values", func(t *testing.T) {
+		overridden := *u
+		overridden.Files = append([]upstream.UpstreamFile{}, u.Files...)
+		overridden.Files = append(overridden.Files, upstream.UpstreamFile{
+			Path:    "userdata/values.yaml",
+			Content: []byte("memcached:\n  enabled: true\n"),
+		})
+
+		b, err := renderHelm(&overridden, &RenderOptions{Namespace: "default"})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"configmap.yaml", "charts/redis/service.yaml", "charts/memcached/service.yaml"}, renderedPaths(b))
+	})
+}
+
+func Test_renderHelmCapabilities(t *testing.T) {
+	u := &upstream.Upstream{
+		Name: "caps",
+		Type: "helm",
+		Files: []upstream.UpstreamFile{
+			{
+				Path:    "Chart.yaml",
+				Content: []byte("apiVersion: v1\nname: caps\nversion: 0.1.0\n"),
+			},
+			{
+				Path: "templates/deployment.yaml",
+				Content: []byte(`apiVersion: {{ if .Capabilities.APIVersions.Has "apps/v1" }}apps/v1{{ else }}extensions/v1beta1{{ end }}
+kind: Deployment
+metadata:
+  name: kube-{{ .Capabilities.KubeVersion.Major }}-{{ .Capabilities.KubeVersion.Minor }}
+`),
+			},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		renderOptions *RenderOptions
+		expected      string
+	}{
+		{
+			name:          "defaults",
+			renderOptions: &RenderOptions{},
+			expected:      "apiVersion: extensions/v1beta1\nkind: Deployment\nmetadata:\n  name: kube-1-16\n",
+		},
+		{
+			name: "from options",
+			renderOptions: &RenderOptions{
+				KubeVersion: "v1.14.3-gke.1",
+				APIVersions: []string{"apps/v1"},
+			},
+			expected: "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: kube-1-14\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := require.New(t)
+
+			b, err := renderHelm(u, test.renderOptions)
+			req.NoError(err)
+			req.Len(b.Files, 1)
+			assert.Equal(t, test.expected, string(b.Files[0].Content))
+		})
+	}
+
+	_, err := renderHelm(u, &RenderOptions{KubeVersion: "not-a-version"})
+	require.Error(t, err)
+}
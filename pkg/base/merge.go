@@ -0,0 +1,302 @@
+package base
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+const (
+	conflictStartMarker = "<<<<<<< local"
+	conflictSeparator   = "======="
+	conflictEndMarker   = ">>>>>>> upstream"
+)
+
+// MergeConflict is a file in base that was changed both locally and in the upstream,
+// in ways that can't be merged
+type MergeConflict struct {
+	Path    string
+	Message string
+}
+
+func (c MergeConflict) Error() string {
+	return fmt.Sprintf("%s: %s", c.Path, c.Message)
+}
+
+// MergeConflicts are all of the files in base that couldn't be merged
+type MergeConflicts []MergeConflict
+
+func (e MergeConflicts) Error() string {
+	lines := []string{"local changes to base conflict with the upstream:"}
+	for _, conflict := range e {
+		lines = append(lines, fmt.Sprintf("  - %s", conflict.Error()))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// mergeLocalChanges does a three-way merge of the files in baseDir, which may have been
+// edited, and the updated files, using the files in pristineDir as the original that both
+// changed from. Files that can't be merged are returned with conflict markers around the
+// local and the updated lines, and are also returned as MergeConflicts
+func mergeLocalChanges(baseDir string, pristineDir string, updated map[string][]byte) (map[string][]byte, MergeConflicts, error) {
+	pristine, err := readDirFiles(pristineDir)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to read pristine files")
+	}
+	if pristine == nil {
+		return nil, nil, errors.Errorf("no pristine copy of the base in %s", pristineDir)
+	}
+
+	local, err := readDirFiles(baseDir)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to read local files")
+	}
+
+	paths := map[string]bool{}
+	for _, files := range []map[string][]byte{pristine, local, updated} {
+		for p := range files {
+			paths[p] = true
+		}
+	}
+	sortedPaths := []string{}
+	for p := range paths {
+		sortedPaths = append(sortedPaths, p)
+	}
+	sort.Strings(sortedPaths)
+
+	merged := map[string][]byte{}
+	var conflicts MergeConflicts
+	for _, p := range sortedPaths {
+		originalContent, inPristine := pristine[p]
+		localContent, inLocal := local[p]
+		updatedContent, inUpdated := updated[p]
+
+		switch {
+		case inLocal == inPristine && bytes.Equal(localContent, originalContent):
+			// not changed locally
+			if inUpdated {
+				merged[p] = updatedContent
+			}
+		case inUpdated == inPristine && bytes.Equal(updatedContent, originalContent):
+			// not changed in the upstream
+			if inLocal {
+				merged[p] = localContent
+			}
+		case inLocal == inUpdated && bytes.Equal(localContent, updatedContent):
+			// changed the same way in both
+			if inLocal {
+				merged[p] = localContent
+			}
+		case !inPristine:
+			conflicts = append(conflicts, MergeConflict{Path: p, Message: "added locally and in the upstream"})
+			merged[p] = conflictContent(splitLines(localContent), splitLines(updatedContent))
+		case !inLocal:
+			conflicts = append(conflicts, MergeConflict{Path: p, Message: "deleted locally and changed in the upstream"})
+			merged[p] = conflictContent(nil, splitLines(updatedContent))
+		case !inUpdated:
+			conflicts = append(conflicts, MergeConflict{Path: p, Message: "changed locally and deleted in the upstream"})
+			merged[p] = conflictContent(splitLines(localContent), nil)
+		default:
+			content, conflictLines := mergeContent(originalContent, localContent, updatedContent)
+			if len(conflictLines) > 0 {
+				lines := []string{}
+				for _, line := range conflictLines {
+					lines = append(lines, fmt.Sprintf("%d", line))
+				}
+				conflicts = append(conflicts, MergeConflict{
+					Path:    p,
+					Message: fmt.Sprintf("changed locally and in the upstream at line %s", strings.Join(lines, ", ")),
+				})
+			}
+			merged[p] = content
+		}
+	}
+
+	return merged, conflicts, nil
+}
+
+// mergeHunk is a range of the original lines, [o1, o2), that one side of a merge
+// replaced with its lines [x1, x2)
+type mergeHunk struct {
+	side   int
+	o1, o2 int
+	x1, x2 int
+}
+
+// mergeContent does a three-way merge of the lines of local and updated. Changes to
+// different lines of the original are both kept. Changes to the same lines are a conflict,
+// unless they are the same. Conflicts are marked in the merged content, and the first
+// original line of each conflict is returned
+func mergeContent(original []byte, local []byte, updated []byte) ([]byte, []int) {
+	originalLines := splitLines(original)
+	sideLines := [2][]string{splitLines(local), splitLines(updated)}
+
+	hunks := []mergeHunk{}
+	for side, lines := range sideLines {
+		matcher := difflib.NewMatcherWithJunk(originalLines, lines, false, nil)
+		for _, opCode := range matcher.GetOpCodes() {
+			if opCode.Tag == 'e' {
+				continue
+			}
+			hunks = append(hunks, mergeHunk{side: side, o1: opCode.I1, o2: opCode.I2, x1: opCode.J1, x2: opCode.J2})
+		}
+	}
+	sort.SliceStable(hunks, func(i, j int) bool {
+		return hunks[i].o1 < hunks[j].o1
+	})
+
+	merged := []string{}
+	var conflicts []int
+	pos := 0
+	for i := 0; i < len(hunks); {
+		// changes that overlap or touch are merged together
+		lo, hi := hunks[i].o1, hunks[i].o2
+		j := i + 1
+		for j < len(hunks) && hunks[j].o1 <= hi {
+			if hunks[j].o2 > hi {
+				hi = hunks[j].o2
+			}
+			j++
+		}
+
+		merged = append(merged, originalLines[pos:lo]...)
+
+		changed := [2]bool{}
+		replacements := [2][]string{}
+		for side := range sideLines {
+			replacements[side], changed[side] = replaceLines(hunks[i:j], side, originalLines, sideLines[side], lo, hi)
+		}
+
+		switch {
+		case !changed[1] || strings.Join(replacements[0], "") == strings.Join(replacements[1], ""):
+			merged = append(merged, replacements[0]...)
+		case !changed[0]:
+			merged = append(merged, replacements[1]...)
+		default:
+			merged = append(merged, markConflict(replacements[0], replacements[1])...)
+			conflicts = append(conflicts, lo+1)
+		}
+
+		pos = hi
+		i = j
+	}
+	merged = append(merged, originalLines[pos:]...)
+
+	return []byte(strings.Join(merged, "")), conflicts
+}
+
+// markConflict returns the local and the updated lines between conflict markers
+func markConflict(localLines []string, updatedLines []string) []string {
+	lines := []string{conflictStartMarker + "\n"}
+	lines = append(lines, withTrailingNewline(localLines)...)
+	lines = append(lines, conflictSeparator+"\n")
+	lines = append(lines, withTrailingNewline(updatedLines)...)
+	lines = append(lines, conflictEndMarker+"\n")
+
+	return lines
+}
+
+// conflictContent returns the whole local and updated content between conflict markers
+func conflictContent(localLines []string, updatedLines []string) []byte {
+	return []byte(strings.Join(markConflict(localLines, updatedLines), ""))
+}
+
+// hasConflictMarkers returns true if content has the start or the end of a conflict
+func hasConflictMarkers(content []byte) bool {
+	for _, line := range splitLines(content) {
+		line = strings.TrimRight(line, "\r\n")
+		if line == conflictStartMarker || line == conflictEndMarker {
+			return true
+		}
+	}
+
+	return false
+}
+
+// withTrailingNewline ends the last line with a newline, so that a conflict marker
+// after it starts on its own line
+func withTrailingNewline(lines []string) []string {
+	if len(lines) == 0 || strings.HasSuffix(lines[len(lines)-1], "\n") {
+		return lines
+	}
+
+	result := append([]string{}, lines...)
+	result[len(result)-1] += "\n"
+	return result
+}
+
+// replaceLines returns the lines of one side that replace the original lines [lo, hi),
+// and whether that side changed them
+func replaceLines(hunks []mergeHunk, side int, originalLines []string, lines []string, lo int, hi int) ([]string, bool) {
+	var first, last *mergeHunk
+	for i := range hunks {
+		if hunks[i].side != side {
+			continue
+		}
+		if first == nil {
+			first = &hunks[i]
+		}
+		last = &hunks[i]
+	}
+
+	if first == nil {
+		return originalLines[lo:hi], false
+	}
+
+	return lines[first.x1-(first.o1-lo) : last.x2+(hi-last.o2)], true
+}
+
+// splitLines splits content into lines that keep their line endings, so that joining
+// them gives the content back
+func splitLines(content []byte) []string {
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+// readDirFiles reads all of the files in dir by their slash separated path in dir,
+// or returns nil if dir doesn't exist
+func readDirFiles(dir string) (map[string][]byte, error) {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	files := map[string][]byte{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read %s", path)
+		}
+
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return errors.Wrap(err, "failed to get relative path")
+		}
+
+		files[filepath.ToSlash(relPath)] = content
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
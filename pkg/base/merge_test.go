@@ -0,0 +1,319 @@
+package base
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_mergeContent(t *testing.T) {
+	original := "a\nb\nc\nd\ne\n"
+
+	tests := []struct {
+		name          string
+		local         string
+		updated       string
+		expected      string
+		conflictLines []int
+	}{
+		{
+			name:     "no changes",
+			local:    original,
+			updated:  original,
+			expected: original,
+		},
+		{
+			name:     "changed locally",
+			local:    "a\nB\nc\nd\ne\n",
+			updated:  original,
+			expected: "a\nB\nc\nd\ne\n",
+		},
+		{
+			name:     "changed in the upstream",
+			local:    original,
+			updated:  "a\nb\nc\nD\ne\n",
+			expected: "a\nb\nc\nD\ne\n",
+		},
+		{
+			name:     "changed different lines",
+			local:    "a\nB\nc\nd\ne\n",
+			updated:  "a\nb\nc\nd\ne\nf\n",
+			expected: "a\nB\nc\nd\ne\nf\n",
+		},
+		{
+			name:     "deleted and inserted lines",
+			local:    "a\nc\nd\ne\n",
+			updated:  "a\nb\nc\nd\nd2\ne\n",
+			expected: "a\nc\nd\nd2\ne\n",
+		},
+		{
+			name:     "changed the same lines the same way",
+			local:    "a\nB\nc\nd\ne\n",
+			updated:  "a\nB\nc\nD\ne\n",
+			expected: "a\nB\nc\nD\ne\n",
+		},
+		{
+			name:          "changed the same lines differently",
+			local:         "a\nB\nc\nd\ne\n",
+			updated:       "a\nbb\nc\nd\nE\n",
+			expected:      "a\n<<<<<<< local\nB\n=======\nbb\n>>>>>>> upstream\nc\nd\nE\n",
+			conflictLines: []int{2},
+		},
+		{
+			name:          "changed the last line differently without a newline",
+			local:         "a\nb\nc\nd\nE",
+			updated:       "a\nb\nc\nd\nee",
+			expected:      "a\nb\nc\nd\n<<<<<<< local\nE\n=======\nee\n>>>>>>> upstream\n",
+			conflictLines: []int{5},
+		},
+		{
+			name:     "no newline at the end",
+			local:    "a\nb\nc\nd\ne",
+			updated:  "a\nB\nc\nd\ne\n",
+			expected: "a\nB\nc\nd\ne",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			merged, conflictLines := mergeContent([]byte(original), []byte(test.local), []byte(test.updated))
+			assert.Equal(t, test.conflictLines, conflictLines)
+			assert.Equal(t, test.expected, string(merged))
+		})
+	}
+}
+
+func TestWriteBaseMergesLocalChanges(t *testing.T) {
+	req := require.New(t)
+
+	appDir, err := ioutil.TempDir("", "kots-base")
+	req.NoError(err)
+	defer os.RemoveAll(appDir)
+
+	options := WriteOptions{
+		BaseDir:   filepath.Join(appDir, "base"),
+		Overwrite: true,
+	}
+
+	deployment := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+        - image: web:1.0.0
+`
+	b := Base{
+		Files: []BaseFile{
+			{Path: "deployment.yaml", Content: []byte(deployment)},
+			{Path: "service.yaml", Content: []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n")},
+		},
+	}
+	req.NoError(b.WriteBase(options))
+
+	// edit the replicas, and delete the service
+	localDeployment := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+        - image: web:1.0.0
+`
+	req.NoError(ioutil.WriteFile(filepath.Join(options.BaseDir, "deployment.yaml"), []byte(localDeployment), 0644))
+	req.NoError(os.Remove(filepath.Join(options.BaseDir, "service.yaml")))
+
+	// the update changes the image
+	b.Files[0].Content = []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+        - image: web:1.1.0
+`)
+	req.NoError(b.WriteBase(options))
+
+	content, err := ioutil.ReadFile(filepath.Join(options.BaseDir, "deployment.yaml"))
+	req.NoError(err)
+	assert.Equal(t, `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+        - image: web:1.1.0
+`, string(content))
+	_, err = os.Stat(filepath.Join(options.BaseDir, "service.yaml"))
+	assert.True(t, os.IsNotExist(err))
+
+	// the pristine copy is the update, without the local changes
+	content, err = ioutil.ReadFile(filepath.Join(b.GetPristineDir(options), "deployment.yaml"))
+	req.NoError(err)
+	assert.Equal(t, string(b.Files[0].Content), string(content))
+
+	// an update to the same line conflicts, and is written with conflict markers
+	b.Files[0].Content = []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+        - image: web:1.1.0
+`)
+	err = b.WriteBase(options)
+	req.Error(err)
+	conflicts, ok := errors.Cause(err).(MergeConflicts)
+	req.True(ok)
+	assert.Equal(t, MergeConflicts{
+		{Path: "deployment.yaml", Message: "changed locally and in the upstream at line 6"},
+	}, conflicts)
+
+	content, err = ioutil.ReadFile(filepath.Join(options.BaseDir, "deployment.yaml"))
+	req.NoError(err)
+	assert.Equal(t, `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+<<<<<<< local
+  replicas: 3
+=======
+  replicas: 2
+>>>>>>> upstream
+  template:
+    spec:
+      containers:
+        - image: web:1.1.0
+`, string(content))
+
+	// the pristine copy is advanced, so the resolved file is a local change to the update
+	content, err = ioutil.ReadFile(filepath.Join(b.GetPristineDir(options), "deployment.yaml"))
+	req.NoError(err)
+	assert.Equal(t, string(b.Files[0].Content), string(content))
+
+	// writing again before the conflict is resolved doesn't change the base
+	err = b.WriteBase(options)
+	req.Error(err)
+	conflicts, ok = errors.Cause(err).(MergeConflicts)
+	req.True(ok)
+	assert.Equal(t, MergeConflicts{
+		{Path: "deployment.yaml", Message: "conflict markers are not resolved"},
+	}, conflicts)
+	content, err = ioutil.ReadFile(filepath.Join(options.BaseDir, "deployment.yaml"))
+	req.NoError(err)
+	assert.Contains(t, string(content), "<<<<<<< local")
+
+	req.NoError(ioutil.WriteFile(filepath.Join(options.BaseDir, "deployment.yaml"), []byte(localDeployment), 0644))
+	req.NoError(CheckLocalChanges(options))
+	req.NoError(b.WriteBase(options))
+
+	content, err = ioutil.ReadFile(filepath.Join(options.BaseDir, "deployment.yaml"))
+	req.NoError(err)
+	assert.Equal(t, localDeployment, string(content))
+
+	// unless the local changes are discarded
+	options.Force = true
+	req.NoError(b.WriteBase(options))
+
+	content, err = ioutil.ReadFile(filepath.Join(options.BaseDir, "deployment.yaml"))
+	req.NoError(err)
+	assert.Equal(t, string(b.Files[0].Content), string(content))
+	_, err = os.Stat(filepath.Join(options.BaseDir, "service.yaml"))
+	assert.NoError(t, err)
+}
+
+func Test_mergeLocalChangesFileConflicts(t *testing.T) {
+	req := require.New(t)
+
+	appDir, err := ioutil.TempDir("", "kots-base")
+	req.NoError(err)
+	defer os.RemoveAll(appDir)
+
+	baseDir := filepath.Join(appDir, "base")
+	req.NoError(writeFiles(getPristineDir(baseDir), map[string][]byte{
+		"deleted-locally.yaml":  []byte("a: 1\n"),
+		"deleted-upstream.yaml": []byte("b: 1\n"),
+	}))
+	req.NoError(writeFiles(baseDir, map[string][]byte{
+		"added.yaml":            []byte("c: local\n"),
+		"deleted-upstream.yaml": []byte("b: local\n"),
+	}))
+
+	merged, conflicts, err := mergeLocalChanges(baseDir, getPristineDir(baseDir), map[string][]byte{
+		"added.yaml":           []byte("c: upstream\n"),
+		"deleted-locally.yaml": []byte("a: upstream\n"),
+	})
+	req.NoError(err)
+	assert.Equal(t, MergeConflicts{
+		{Path: "added.yaml", Message: "added locally and in the upstream"},
+		{Path: "deleted-locally.yaml", Message: "deleted locally and changed in the upstream"},
+		{Path: "deleted-upstream.yaml", Message: "changed locally and deleted in the upstream"},
+	}, conflicts)
+	assert.Equal(t, map[string][]byte{
+		"added.yaml":            []byte("<<<<<<< local\nc: local\n=======\nc: upstream\n>>>>>>> upstream\n"),
+		"deleted-locally.yaml":  []byte("<<<<<<< local\n=======\na: upstream\n>>>>>>> upstream\n"),
+		"deleted-upstream.yaml": []byte("<<<<<<< local\nb: local\n=======\n>>>>>>> upstream\n"),
+	}, merged)
+}
+
+func TestWriteBaseWithoutPristine(t *testing.T) {
+	req := require.New(t)
+
+	appDir, err := ioutil.TempDir("", "kots-base")
+	req.NoError(err)
+	defer os.RemoveAll(appDir)
+
+	options := WriteOptions{
+		BaseDir:   filepath.Join(appDir, "base"),
+		Overwrite: true,
+	}
+	req.NoError(CheckLocalChanges(options))
+
+	// a base that was written before the pristine copy was kept
+	req.NoError(writeFiles(options.BaseDir, map[string][]byte{
+		"service.yaml": []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: local\n"),
+	}))
+
+	b := Base{
+		Files: []BaseFile{
+			{Path: "service.yaml", Content: []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n")},
+		},
+	}
+	assert.Equal(t, ErrNoPristineBase, CheckLocalChanges(options))
+	assert.Equal(t, ErrNoPristineBase, b.WriteBase(options))
+
+	content, err := ioutil.ReadFile(filepath.Join(options.BaseDir, "service.yaml"))
+	req.NoError(err)
+	assert.Contains(t, string(content), "name: local")
+
+	options.Force = true
+	req.NoError(CheckLocalChanges(options))
+	req.NoError(b.WriteBase(options))
+
+	content, err = ioutil.ReadFile(filepath.Join(options.BaseDir, "service.yaml"))
+	req.NoError(err)
+	assert.Contains(t, string(content), "name: web")
+	_, err = os.Stat(b.GetPristineDir(options))
+	assert.NoError(t, err)
+}
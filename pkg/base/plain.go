@@ -0,0 +1,23 @@
+package base
+
+import (
+	"github.com/replicatedhq/kots/pkg/upstream"
+)
+
+// renderPlain is used for upstreams that are already valid kubernetes yaml,
+// the files are passed through to the base unchanged
+func renderPlain(u *upstream.Upstream, renderOptions *RenderOptions) (*Base, error) {
+	baseFiles := []BaseFile{}
+	for _, upstreamFile := range u.Files {
+		baseFile := BaseFile{
+			Path:    upstreamFile.Path,
+			Content: upstreamFile.Content,
+		}
+
+		baseFiles = append(baseFiles, baseFile)
+	}
+
+	return &Base{
+		Files: baseFiles,
+	}, nil
+}
@@ -8,7 +8,15 @@ import (
 type RenderOptions struct {
 	SplitMultiDocYAML bool
 	Namespace         string
-	HelmOptions       []string
+	KubeVersion       string
+	APIVersions       []string
+	ValidateConfig    bool
+	// Kubeconfig is the cluster that templates can read. It's not used when there is a
+	// ClusterInfoFile, which is for rendering offline
+	Kubeconfig      string
+	ClusterInfoFile string
+	// Strict makes undefined config items and values that can't be converted an error
+	Strict bool
 }
 
 // RenderUpstream is responsible for any conversions or transpilation steps are required
@@ -22,5 +30,9 @@ func RenderUpstream(u *upstream.Upstream, renderOptions *RenderOptions) (*Base,
 		return renderReplicated(u, renderOptions)
 	}
 
+	if u.Type == "git" || u.Type == "http" || u.Type == "manifests" {
+		return renderPlain(u, renderOptions)
+	}
+
 	return nil, errors.New("unknown upstream type")
 }
@@ -1,11 +1,19 @@
 package base
 
 import (
+	"bytes"
+	"strconv"
+	"strings"
+
 	"github.com/pkg/errors"
 	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
 	kotsscheme "github.com/replicatedhq/kots/kotskinds/client/kotsclientset/scheme"
+	kotsconfig "github.com/replicatedhq/kots/pkg/config"
+	"github.com/replicatedhq/kots/pkg/k8sutil"
 	"github.com/replicatedhq/kots/pkg/template"
 	"github.com/replicatedhq/kots/pkg/upstream"
+	"github.com/replicatedhq/kots/pkg/util"
+	"gopkg.in/yaml.v2"
 	"k8s.io/client-go/kubernetes/scheme"
 )
 
@@ -13,7 +21,7 @@ func renderReplicated(u *upstream.Upstream, renderOptions *RenderOptions) (*Base
 	// Find the config for the config groups
 	var config *kotsv1beta1.Config
 	for _, upstreamFile := range u.Files {
-		maybeConfig := tryGetConfigFromFileContent(upstreamFile.Content)
+		maybeConfig := kotsconfig.TryParseConfig(upstreamFile.Content)
 		if maybeConfig != nil {
 			config = maybeConfig
 		}
@@ -23,7 +31,7 @@ func renderReplicated(u *upstream.Upstream, renderOptions *RenderOptions) (*Base
 	var templateContext map[string]interface{}
 	for _, c := range u.Files {
 		if c.Path == "userdata/config.yaml" {
-			ctx, err := unmarshalConfigValuesContent(c.Content)
+			ctx, err := unmarshalConfigValuesContent(c.Content, u.EncryptionKey)
 			if err != nil {
 				return nil, errors.Wrap(err, "failed to unmarshal config values content")
 			}
@@ -32,16 +40,45 @@ func renderReplicated(u *upstream.Upstream, renderOptions *RenderOptions) (*Base
 		}
 	}
 
+	// Find the license for the entitlements
+	var license *kotsv1beta1.License
+	for _, c := range u.Files {
+		if c.Path == "userdata/license.yaml" {
+			license = tryGetLicenseFromFileContent(c.Content)
+		}
+	}
+
 	baseFiles := []BaseFile{}
 
-	builder := template.Builder{}
-	builder.AddCtx(template.StaticCtx{})
+	builder := template.Builder{
+		Strict: renderOptions.Strict,
+	}
+	builder.AddCtx(template.StaticCtx{GeneratedValues: u.GeneratedValues})
+	// the license functions are defined without a license too, so that config items that
+	// use them render to an empty value
+	builder.AddCtx(template.LicenseCtx{License: license})
+
+	defaultClusterInfo := &k8sutil.ClusterInfo{
+		KubeVersion: renderOptions.KubeVersion,
+		APIVersions: renderOptions.APIVersions,
+	}
+	kubernetesCtx, err := template.NewKubernetesCtx(renderOptions.Namespace, renderOptions.Kubeconfig, renderOptions.ClusterInfoFile, defaultClusterInfo)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create kubernetes context")
+	}
+	builder.AddCtx(kubernetesCtx)
 
 	if config != nil {
 		configCtx, err := builder.NewConfigContext(config.Spec.Groups, templateContext)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to create config context")
 		}
+
+		if renderOptions.ValidateConfig {
+			if validationErrors := kotsconfig.ValidateConfig(config.Spec.Groups, configCtx.ItemValues); len(validationErrors) > 0 {
+				return nil, validationErrors
+			}
+		}
 		builder.AddCtx(configCtx)
 	}
 
@@ -51,9 +88,17 @@ func renderReplicated(u *upstream.Upstream, renderOptions *RenderOptions) (*Base
 			return nil, errors.Wrap(err, "failed to render template")
 		}
 
+		content, err := removeExcludedDocuments([]byte(rendered))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to evaluate %s in %s", WhenAnnotation, upstreamFile.Path)
+		}
+		if content == nil {
+			continue
+		}
+
 		baseFile := BaseFile{
 			Path:    upstreamFile.Path,
-			Content: []byte(rendered),
+			Content: content,
 		}
 
 		baseFiles = append(baseFiles, baseFile)
@@ -66,29 +111,71 @@ func renderReplicated(u *upstream.Upstream, renderOptions *RenderOptions) (*Base
 	return &base, nil
 }
 
-func unmarshalConfigValuesContent(content []byte) (map[string]interface{}, error) {
-	kotsscheme.AddToScheme(scheme.Scheme)
-	decode := scheme.Codecs.UniversalDeserializer().Decode
-	obj, gvk, err := decode(content, nil, nil)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to decode values")
+// WhenAnnotation is the annotation on upstream manifests that excludes the manifest
+// from the base when it renders to false
+const WhenAnnotation = "kots.io/when"
+
+type overlySimpleMetadata struct {
+	Metadata struct {
+		Annotations map[string]string `yaml:"annotations"`
+	} `yaml:"metadata"`
+}
+
+// removeExcludedDocuments removes the yaml documents that have a when annotation that
+// rendered to false, and returns nil when all documents are removed
+func removeExcludedDocuments(content []byte) ([]byte, error) {
+	docs := util.SplitYAML(content)
+
+	included := [][]byte{}
+	for _, doc := range docs {
+		o := overlySimpleMetadata{}
+		if err := yaml.Unmarshal(doc, &o); err != nil {
+			// not yaml, so there's no annotation
+			included = append(included, doc)
+			continue
+		}
+
+		when, ok := o.Metadata.Annotations[WhenAnnotation]
+		if !ok || strings.TrimSpace(when) == "" {
+			included = append(included, doc)
+			continue
+		}
+
+		include, err := strconv.ParseBool(strings.TrimSpace(when))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse %q", when)
+		}
+		if include {
+			included = append(included, doc)
+		}
 	}
 
-	if gvk.Group != "kots.io" || gvk.Version != "v1beta1" || gvk.Kind != "ConfigValues" {
-		return nil, errors.New("not a configvalues object")
+	if len(included) == len(docs) {
+		return content, nil
+	}
+	if len(included) == 0 {
+		return nil, nil
 	}
 
-	values := obj.(*kotsv1beta1.ConfigValues)
+	return bytes.Join(included, []byte("---\n")), nil
+}
 
-	ctx := map[string]interface{}{}
-	for k, v := range values.Spec.Values {
-		ctx[k] = v
+// unmarshalConfigValuesContent returns the config values as a template context, with
+// the encrypted values decrypted
+func unmarshalConfigValuesContent(content []byte, encryptionKey string) (map[string]interface{}, error) {
+	values, err := kotsconfig.ParseConfigValues(content)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse values")
 	}
 
-	return ctx, nil
+	if err := kotsconfig.DecryptConfigValues(values, encryptionKey); err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt values")
+	}
+
+	return kotsconfig.TemplateContext(values), nil
 }
 
-func tryGetConfigFromFileContent(content []byte) *kotsv1beta1.Config {
+func tryGetLicenseFromFileContent(content []byte) *kotsv1beta1.License {
 	kotsscheme.AddToScheme(scheme.Scheme)
 	decode := scheme.Codecs.UniversalDeserializer().Decode
 	obj, gvk, err := decode(content, nil, nil)
@@ -96,12 +183,8 @@ func tryGetConfigFromFileContent(content []byte) *kotsv1beta1.Config {
 		return nil
 	}
 
-	if gvk.Group == "kots.io" {
-		if gvk.Version == "v1beta1" {
-			if gvk.Kind == "Config" {
-				return obj.(*kotsv1beta1.Config)
-			}
-		}
+	if gvk.Group == "kots.io" && gvk.Version == "v1beta1" && gvk.Kind == "License" {
+		return obj.(*kotsv1beta1.License)
 	}
 
 	return nil
@@ -0,0 +1,295 @@
+package base
+
+import (
+	"testing"
+
+	"github.com/replicatedhq/kots/pkg/crypto"
+	"github.com/replicatedhq/kots/pkg/upstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_removeExcludedDocuments(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		expected    string
+		expectNil   bool
+		expectError bool
+	}{
+		{
+			name:     "no annotation",
+			content:  "apiVersion: v1\nkind: Service\nmetadata:\n  name: svc\n",
+			expected: "apiVersion: v1\nkind: Service\nmetadata:\n  name: svc\n",
+		},
+		{
+			name:     "not yaml",
+			content:  "this is a notes.txt\n\tfrom: [helm\n",
+			expected: "this is a notes.txt\n\tfrom: [helm\n",
+		},
+		{
+			name:     "included",
+			content:  "apiVersion: v1\nkind: Service\nmetadata:\n  name: svc\n  annotations:\n    kots.io/when: 'true'\n",
+			expected: "apiVersion: v1\nkind: Service\nmetadata:\n  name: svc\n  annotations:\n    kots.io/when: 'true'\n",
+		},
+		{
+			name:      "excluded",
+			content:   "apiVersion: v1\nkind: Service\nmetadata:\n  name: svc\n  annotations:\n    kots.io/when: 'false'\n",
+			expectNil: true,
+		},
+		{
+			name:     "multi doc",
+			content:  "apiVersion: v1\nkind: Service\nmetadata:\n  name: a\n  annotations:\n    kots.io/when: 'false'\n---\napiVersion: v1\nkind: Service\nmetadata:\n  name: b\n",
+			expected: "apiVersion: v1\nkind: Service\nmetadata:\n  name: b\n",
+		},
+		{
+			name:     "leading separator",
+			content:  "---\napiVersion: v1\nkind: Service\nmetadata:\n  name: a\n  annotations:\n    kots.io/when: 'false'\n---\napiVersion: v1\nkind: Service\nmetadata:\n  name: b\n",
+			expected: "apiVersion: v1\nkind: Service\nmetadata:\n  name: b\n",
+		},
+		{
+			name:     "windows line endings",
+			content:  "apiVersion: v1\r\nkind: Service\r\nmetadata:\r\n  name: a\r\n  annotations:\r\n    kots.io/when: 'false'\r\n---\r\napiVersion: v1\r\nkind: Service\r\nmetadata:\r\n  name: b\r\n",
+			expected: "apiVersion: v1\nkind: Service\nmetadata:\n  name: b\n",
+		},
+		{
+			name:     "three docs",
+			content:  "kind: A\n---\nkind: B\nmetadata:\n  annotations:\n    kots.io/when: 'false'\n---\nkind: C",
+			expected: "kind: A\n---\nkind: C",
+		},
+		{
+			name:        "invalid",
+			content:     "apiVersion: v1\nkind: Service\nmetadata:\n  name: svc\n  annotations:\n    kots.io/when: 'maybe'\n",
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := require.New(t)
+
+			content, err := removeExcludedDocuments([]byte(test.content))
+			if test.expectError {
+				req.Error(err)
+				return
+			}
+			req.NoError(err)
+
+			if test.expectNil {
+				assert.Nil(t, content)
+				return
+			}
+			assert.Equal(t, test.expected, string(content))
+		})
+	}
+}
+
+func Test_renderReplicatedWhen(t *testing.T) {
+	req := require.New(t)
+
+	u := &upstream.Upstream{
+		Type: "replicated",
+		Files: []upstream.UpstreamFile{
+			{
+				Path: "config.yaml",
+				Content: []byte(`apiVersion: kots.io/v1beta1
+kind: Config
+metadata:
+  name: app
+spec:
+  groups:
+  - name: database
+    title: Database
+    items:
+    - name: postgres_type
+      type: select_one
+      default: embedded
+    - name: external_host
+      type: text
+      default: db.example.com
+      when: '{{repl ConfigOptionEquals "postgres_type" "external"}}'
+`),
+			},
+			{
+				Path: "postgres.yaml",
+				Content: []byte(`apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: postgres
+  annotations:
+    kots.io/when: '{{repl ConfigOptionEquals "postgres_type" "embedded"}}'
+`),
+			},
+			{
+				Path: "external.yaml",
+				Content: []byte(`apiVersion: v1
+kind: Service
+metadata:
+  name: external
+  annotations:
+    kots.io/when: '{{repl ConfigOptionEquals "postgres_type" "external"}}'
+spec:
+  externalName: '{{repl ConfigOption "external_host"}}'
+`),
+			},
+		},
+	}
+
+	b, err := renderReplicated(u, &RenderOptions{})
+	req.NoError(err)
+
+	paths := []string{}
+	for _, f := range b.Files {
+		paths = append(paths, f.Path)
+	}
+	assert.ElementsMatch(t, []string{"config.yaml", "postgres.yaml"}, paths)
+}
+
+func Test_renderReplicatedValidateConfig(t *testing.T) {
+	u := &upstream.Upstream{
+		Type: "replicated",
+		Files: []upstream.UpstreamFile{
+			{
+				Path: "config.yaml",
+				Content: []byte(`apiVersion: kots.io/v1beta1
+kind: Config
+metadata:
+  name: app
+spec:
+  groups:
+  - name: database
+    title: Database
+    items:
+    - name: hostname
+      type: text
+      required: true
+`),
+			},
+		},
+	}
+
+	_, err := renderReplicated(u, &RenderOptions{})
+	require.NoError(t, err)
+
+	_, err = renderReplicated(u, &RenderOptions{ValidateConfig: true})
+	require.Error(t, err)
+	assert.Equal(t, "config values are invalid:\n  - database/hostname: a value is required", err.Error())
+}
+
+func Test_renderReplicatedStrict(t *testing.T) {
+	u := &upstream.Upstream{
+		Type: "replicated",
+		Files: []upstream.UpstreamFile{
+			{
+				Path: "config.yaml",
+				Content: []byte(`apiVersion: kots.io/v1beta1
+kind: Config
+metadata:
+  name: app
+spec:
+  groups:
+  - name: database
+    title: Database
+    items:
+    - name: url
+      type: text
+      default: 'postgres://{{repl ConfigOption "hostnme"}}'
+    - name: hostname
+      type: text
+      default: db.example.com
+`),
+			},
+		},
+	}
+
+	_, err := renderReplicated(u, &RenderOptions{})
+	require.NoError(t, err)
+
+	_, err = renderReplicated(u, &RenderOptions{Strict: true})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to render default of config item url")
+	assert.Contains(t, err.Error(), "config item hostnme is not defined")
+}
+
+func Test_unmarshalConfigValuesContent(t *testing.T) {
+	encryptionKey := "tUg2e934J7ethZNX2WDaiUNd0E1Z2iJ3SyQEt81zzxIiM48K"
+	encrypted, err := crypto.Encrypt(encryptionKey, "hunter2")
+	require.NoError(t, err)
+
+	content := []byte(`apiVersion: kots.io/v1beta1
+kind: ConfigValues
+metadata:
+  name: app
+spec:
+  values:
+    hostname: db.example.com
+    password: ` + encrypted + `
+  multiValues:
+    cidrs:
+    - 10.0.0.0/8
+    - 192.168.0.0/16
+`)
+
+	ctx, err := unmarshalConfigValuesContent(content, encryptionKey)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"hostname": "db.example.com",
+		"password": "hunter2",
+		"cidrs":    []string{"10.0.0.0/8", "192.168.0.0/16"},
+	}, ctx)
+
+	_, err = unmarshalConfigValuesContent(content, "")
+	require.Error(t, err)
+}
+
+func Test_renderReplicatedLicense(t *testing.T) {
+	req := require.New(t)
+
+	u := &upstream.Upstream{
+		Type: "replicated",
+		Files: []upstream.UpstreamFile{
+			{
+				Path: "userdata/license.yaml",
+				Content: []byte(`apiVersion: kots.io/v1beta1
+kind: License
+metadata:
+  name: app
+spec:
+  appSlug: app
+  licenseID: abc123
+  signature: IA==
+  entitlements:
+    seats:
+      title: Seats
+      value: 25
+    tier:
+      value: gold
+`),
+			},
+			{
+				Path: "deployment.yaml",
+				Content: []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  labels:
+    tier: '{{repl LicenseFieldValue "tier"}}'
+spec:
+  replicas: {{repl LicenseFieldValue "seats"}}
+`),
+			},
+		},
+	}
+
+	b, err := renderReplicated(u, &RenderOptions{})
+	req.NoError(err)
+
+	for _, f := range b.Files {
+		if f.Path == "deployment.yaml" {
+			assert.Contains(t, string(f.Content), "tier: 'gold'")
+			assert.Contains(t, string(f.Content), "replicas: 25")
+			return
+		}
+	}
+	t.Fatal("deployment.yaml was not rendered")
+}
@@ -7,30 +7,175 @@ import (
 	"path"
 
 	"github.com/pkg/errors"
-	"github.com/replicatedhq/kots/pkg/k8sutil"
 	kustomizetypes "sigs.k8s.io/kustomize/v3/pkg/types"
+	"sigs.k8s.io/yaml"
 )
 
+// ErrNoPristineBase is returned when the base has no pristine copy to merge local changes with
+var ErrNoPristineBase = errors.New("the base has no pristine copy to merge local changes with")
+
 type WriteOptions struct {
 	BaseDir          string
 	Overwrite        bool
 	ExcludeKotsKinds bool
+	// Force discards local changes to the base, instead of merging them
+	Force bool
 }
 
+// WriteBase writes the base, and a pristine copy of it. When the base is overwritten,
+// local changes to it are merged with the new base unless Force is set. Changes that
+// conflict are written with conflict markers and returned as MergeConflicts
 func (b *Base) WriteBase(options WriteOptions) error {
 	renderDir := options.BaseDir
 
-	_, err := os.Stat(renderDir)
+	files, err := b.getFilesToWrite(options)
+	if err != nil {
+		return errors.Wrap(err, "failed to get files to write")
+	}
+	pristineFiles := files
+
+	var conflicts MergeConflicts
+	_, err = os.Stat(renderDir)
 	if err == nil {
-		if options.Overwrite {
-			if err := os.RemoveAll(renderDir); err != nil {
-				return errors.Wrap(err, "failed to remove previous content in base")
-			}
-		} else {
+		if !options.Overwrite {
 			return fmt.Errorf("directory %s already exists", renderDir)
 		}
+
+		if err := CheckLocalChanges(options); err != nil {
+			return err
+		}
+
+		if !options.Force {
+			mergedFiles, mergeConflicts, err := mergeLocalChanges(renderDir, getPristineDir(renderDir), files)
+			if err != nil {
+				return errors.Wrap(err, "failed to merge local changes")
+			}
+			files = mergedFiles
+			conflicts = mergeConflicts
+		}
+
+		if err := os.RemoveAll(renderDir); err != nil {
+			return errors.Wrap(err, "failed to remove previous content in base")
+		}
+	}
+
+	if err := writeFiles(renderDir, files); err != nil {
+		return errors.Wrap(err, "failed to write base files")
+	}
+
+	if err := os.RemoveAll(getPristineDir(renderDir)); err != nil {
+		return errors.Wrap(err, "failed to remove previous pristine base")
+	}
+	if err := writeFiles(getPristineDir(renderDir), pristineFiles); err != nil {
+		return errors.Wrap(err, "failed to write pristine base files")
+	}
+
+	// the conflicts are kept until they're resolved, because the pristine copy no longer
+	// has the lines that conflicted
+	if len(conflicts) > 0 {
+		if err := writeConflicts(renderDir, conflicts); err != nil {
+			return errors.Wrap(err, "failed to write conflicts")
+		}
+		return conflicts
+	}
+	if err := os.RemoveAll(getConflictsFile(renderDir)); err != nil {
+		return errors.Wrap(err, "failed to remove resolved conflicts")
+	}
+
+	return nil
+}
+
+// CheckLocalChanges returns an error when there is a base in options.BaseDir without a
+// pristine copy, because local changes to it can't be merged with an update. Apps that
+// were pulled before the pristine copy was kept have to be pulled with Force once.
+// Conflicts from a previous merge that still have conflict markers are returned as
+// MergeConflicts
+func CheckLocalChanges(options WriteOptions) error {
+	if options.Force {
+		return nil
+	}
+
+	if _, err := os.Stat(options.BaseDir); os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return errors.Wrap(err, "failed to stat base")
+	}
+
+	if _, err := os.Stat(getPristineDir(options.BaseDir)); os.IsNotExist(err) {
+		return ErrNoPristineBase
+	} else if err != nil {
+		return errors.Wrap(err, "failed to stat pristine base")
+	}
+
+	unresolved, err := getUnresolvedConflicts(options.BaseDir)
+	if err != nil {
+		return errors.Wrap(err, "failed to get unresolved conflicts")
+	}
+	if len(unresolved) > 0 {
+		return unresolved
+	}
+
+	return nil
+}
+
+// writeConflicts keeps the paths of the files with conflicts
+func writeConflicts(baseDir string, conflicts MergeConflicts) error {
+	paths := []string{}
+	for _, conflict := range conflicts {
+		paths = append(paths, conflict.Path)
+	}
+
+	b, err := yaml.Marshal(paths)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal conflicts")
+	}
+
+	if err := ioutil.WriteFile(getConflictsFile(baseDir), b, 0644); err != nil {
+		return errors.Wrap(err, "failed to write conflicts file")
+	}
+
+	return nil
+}
+
+// getUnresolvedConflicts returns the files with conflicts from the last merge that still
+// have conflict markers. A file that was deleted is resolved
+func getUnresolvedConflicts(baseDir string) (MergeConflicts, error) {
+	b, err := ioutil.ReadFile(getConflictsFile(baseDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read conflicts file")
+	}
+
+	paths := []string{}
+	if err := yaml.Unmarshal(b, &paths); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal conflicts")
+	}
+
+	var unresolved MergeConflicts
+	for _, p := range paths {
+		content, err := ioutil.ReadFile(path.Join(baseDir, p))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read %s", p)
+		}
+
+		if hasConflictMarkers(content) {
+			unresolved = append(unresolved, MergeConflict{Path: p, Message: "conflict markers are not resolved"})
+		}
 	}
 
+	return unresolved, nil
+}
+
+// getFilesToWrite returns the content of the files in the base dir by their path,
+// including the kustomization
+func (b *Base) getFilesToWrite(options WriteOptions) (map[string][]byte, error) {
+	files := map[string][]byte{}
+
 	kustomizeResources := []string{}
 	for _, file := range b.Files {
 		writeToBase := file.ShouldBeIncludedInBaseFilesystem(options.ExcludeKotsKinds)
@@ -45,17 +190,7 @@ func (b *Base) WriteBase(options WriteOptions) error {
 		}
 
 		if writeToBase {
-			fileRenderPath := path.Join(renderDir, file.Path)
-			d, _ := path.Split(fileRenderPath)
-			if _, err := os.Stat(d); os.IsNotExist(err) {
-				if err := os.MkdirAll(d, 0744); err != nil {
-					return errors.Wrap(err, "failed to mkdir")
-				}
-			}
-
-			if err := ioutil.WriteFile(fileRenderPath, file.Content, 0644); err != nil {
-				return errors.Wrap(err, "failed to write base file")
-			}
+			files[path.Clean(file.Path)] = file.Content
 		}
 	}
 
@@ -67,8 +202,28 @@ func (b *Base) WriteBase(options WriteOptions) error {
 		Resources: kustomizeResources,
 	}
 
-	if err := k8sutil.WriteKustomizationToFile(&kustomization, path.Join(renderDir, "kustomization.yaml")); err != nil {
-		return errors.Wrap(err, "failed to write kustomization to file")
+	kustomizationContent, err := yaml.Marshal(kustomization)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal kustomization")
+	}
+	files["kustomization.yaml"] = kustomizationContent
+
+	return files, nil
+}
+
+func writeFiles(dir string, files map[string][]byte) error {
+	for filePath, content := range files {
+		fileRenderPath := path.Join(dir, filePath)
+		d, _ := path.Split(fileRenderPath)
+		if _, err := os.Stat(d); os.IsNotExist(err) {
+			if err := os.MkdirAll(d, 0744); err != nil {
+				return errors.Wrap(err, "failed to mkdir")
+			}
+		}
+
+		if err := ioutil.WriteFile(fileRenderPath, content, 0644); err != nil {
+			return errors.Wrap(err, "failed to write file")
+		}
 	}
 
 	return nil
@@ -79,3 +234,17 @@ func (b *Base) GetOverlaysDir(options WriteOptions) string {
 
 	return path.Join(renderDir, "..", "overlays")
 }
+
+// GetPristineDir returns the dir with the base as it was last written, before any local
+// changes, which is the original for merging local changes with updates
+func (b *Base) GetPristineDir(options WriteOptions) string {
+	return getPristineDir(options.BaseDir)
+}
+
+func getPristineDir(baseDir string) string {
+	return path.Join(baseDir, "..", ".kots", "pristine", "base")
+}
+
+func getConflictsFile(baseDir string) string {
+	return path.Join(baseDir, "..", ".kots", "pristine", "base-conflicts.yaml")
+}
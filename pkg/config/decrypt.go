@@ -0,0 +1,50 @@
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
+	kotsscheme "github.com/replicatedhq/kots/kotskinds/client/kotsclientset/scheme"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// ReadDecryptedConfigValues reads the config values of the app in rootDir, and decrypts
+// them with the encryption key of the installation
+func ReadDecryptedConfigValues(rootDir string) (*kotsv1beta1.ConfigValues, error) {
+	kotsscheme.AddToScheme(scheme.Scheme)
+	decode := scheme.Codecs.UniversalDeserializer().Decode
+
+	installationContent, err := ioutil.ReadFile(filepath.Join(rootDir, "upstream", "userdata", "installation.yaml"))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read installation")
+	}
+	obj, _, err := decode(installationContent, nil, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode installation")
+	}
+	installation, ok := obj.(*kotsv1beta1.Installation)
+	if !ok {
+		return nil, errors.New("installation file is not an installation object")
+	}
+
+	configValuesContent, err := ioutil.ReadFile(filepath.Join(rootDir, "upstream", "userdata", "config.yaml"))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read config values")
+	}
+	obj, _, err = decode(configValuesContent, nil, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode config values")
+	}
+	configValues, ok := obj.(*kotsv1beta1.ConfigValues)
+	if !ok {
+		return nil, errors.New("config values file is not a configvalues object")
+	}
+
+	if err := DecryptConfigValues(configValues, installation.Spec.EncryptionKey); err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt config values")
+	}
+
+	return configValues, nil
+}
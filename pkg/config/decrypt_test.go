@@ -0,0 +1,50 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/replicatedhq/kots/pkg/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadDecryptedConfigValues(t *testing.T) {
+	req := require.New(t)
+
+	encryptionKey := "tUg2e934J7ethZNX2WDaiUNd0E1Z2iJ3SyQEt81zzxIiM48K"
+	encrypted, err := crypto.Encrypt(encryptionKey, "hunter2")
+	req.NoError(err)
+
+	rootDir, err := ioutil.TempDir("", "kots")
+	req.NoError(err)
+	defer os.RemoveAll(rootDir)
+
+	userdataDir := filepath.Join(rootDir, "upstream", "userdata")
+	req.NoError(os.MkdirAll(userdataDir, 0755))
+	req.NoError(ioutil.WriteFile(filepath.Join(userdataDir, "installation.yaml"), []byte(`apiVersion: kots.io/v1beta1
+kind: Installation
+metadata:
+  name: app
+spec:
+  encryptionKey: `+encryptionKey+`
+`), 0644))
+	req.NoError(ioutil.WriteFile(filepath.Join(userdataDir, "config.yaml"), []byte(`apiVersion: kots.io/v1beta1
+kind: ConfigValues
+metadata:
+  name: app
+spec:
+  values:
+    hostname: db.example.com
+    password: `+encrypted+`
+`), 0644))
+
+	configValues, err := ReadDecryptedConfigValues(rootDir)
+	req.NoError(err)
+	assert.Equal(t, map[string]string{
+		"hostname": "db.example.com",
+		"password": "hunter2",
+	}, configValues.Spec.Values)
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"github.com/pkg/errors"
+	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
+	"github.com/replicatedhq/kots/pkg/crypto"
+)
+
+// EncryptConfigValues encrypts the values of password items with the installation
+// encryption key. Values that are already encrypted are not changed
+func EncryptConfigValues(configGroups []kotsv1beta1.ConfigGroup, configValues *kotsv1beta1.ConfigValues, encryptionKey string) error {
+	for _, configGroup := range configGroups {
+		for _, configItem := range configGroup.Items {
+			if configItem.Type != "password" {
+				continue
+			}
+
+			if value, ok := configValues.Spec.Values[configItem.Name]; ok {
+				encrypted, err := encryptValue(encryptionKey, value)
+				if err != nil {
+					return errors.Wrapf(err, "failed to encrypt value of %s", configItem.Name)
+				}
+				configValues.Spec.Values[configItem.Name] = encrypted
+			}
+
+			for i, value := range configValues.Spec.MultiValues[configItem.Name] {
+				encrypted, err := encryptValue(encryptionKey, value)
+				if err != nil {
+					return errors.Wrapf(err, "failed to encrypt value of %s", configItem.Name)
+				}
+				configValues.Spec.MultiValues[configItem.Name][i] = encrypted
+			}
+		}
+	}
+
+	return nil
+}
+
+// DecryptConfigValues decrypts all of the encrypted values
+func DecryptConfigValues(configValues *kotsv1beta1.ConfigValues, encryptionKey string) error {
+	for name, value := range configValues.Spec.Values {
+		decrypted, err := crypto.Decrypt(encryptionKey, value)
+		if err != nil {
+			return errors.Wrapf(err, "failed to decrypt value of %s", name)
+		}
+		configValues.Spec.Values[name] = decrypted
+	}
+
+	for name, values := range configValues.Spec.MultiValues {
+		for i, value := range values {
+			decrypted, err := crypto.Decrypt(encryptionKey, value)
+			if err != nil {
+				return errors.Wrapf(err, "failed to decrypt value of %s", name)
+			}
+			values[i] = decrypted
+		}
+	}
+
+	return nil
+}
+
+// encryptValue encrypts the value unless it's empty or already encrypted
+func encryptValue(encryptionKey string, value string) (string, error) {
+	if value == "" || crypto.IsEncrypted(value) {
+		return value, nil
+	}
+
+	return crypto.Encrypt(encryptionKey, value)
+}
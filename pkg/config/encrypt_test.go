@@ -0,0 +1,62 @@
+package config
+
+import (
+	"testing"
+
+	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
+	"github.com/replicatedhq/kots/pkg/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptConfigValues(t *testing.T) {
+	req := require.New(t)
+
+	encryptionKey := "tUg2e934J7ethZNX2WDaiUNd0E1Z2iJ3SyQEt81zzxIiM48K"
+	configGroups := []kotsv1beta1.ConfigGroup{
+		{
+			Name: "database",
+			Items: []kotsv1beta1.ConfigItem{
+				{Name: "hostname", Type: "text"},
+				{Name: "password", Type: "password"},
+				{Name: "empty_password", Type: "password"},
+				{Name: "replica_passwords", Type: "password", Multiple: true},
+			},
+		},
+	}
+
+	configValues := &kotsv1beta1.ConfigValues{
+		Spec: kotsv1beta1.ConfigValuesSpec{
+			Values: map[string]string{
+				"hostname":       "db.example.com",
+				"password":       "hunter2",
+				"empty_password": "",
+			},
+			MultiValues: map[string][]string{
+				"replica_passwords": {"one", "two"},
+			},
+		},
+	}
+
+	req.NoError(EncryptConfigValues(configGroups, configValues, encryptionKey))
+	assert.Equal(t, "db.example.com", configValues.Spec.Values["hostname"])
+	assert.Equal(t, "", configValues.Spec.Values["empty_password"])
+	assert.True(t, crypto.IsEncrypted(configValues.Spec.Values["password"]))
+	assert.True(t, crypto.IsEncrypted(configValues.Spec.MultiValues["replica_passwords"][0]))
+	assert.True(t, crypto.IsEncrypted(configValues.Spec.MultiValues["replica_passwords"][1]))
+
+	// encrypted values are not encrypted again
+	encrypted := configValues.DeepCopy()
+	req.NoError(EncryptConfigValues(configGroups, configValues, encryptionKey))
+	assert.Equal(t, encrypted, configValues)
+
+	req.NoError(DecryptConfigValues(configValues, encryptionKey))
+	assert.Equal(t, map[string]string{
+		"hostname":       "db.example.com",
+		"password":       "hunter2",
+		"empty_password": "",
+	}, configValues.Spec.Values)
+	assert.Equal(t, map[string][]string{
+		"replica_passwords": {"one", "two"},
+	}, configValues.Spec.MultiValues)
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"github.com/pkg/errors"
+	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
+	kotsscheme "github.com/replicatedhq/kots/kotskinds/client/kotsclientset/scheme"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// ParseConfigValues decodes content that must be a kots.io/v1beta1 ConfigValues object
+func ParseConfigValues(content []byte) (*kotsv1beta1.ConfigValues, error) {
+	kotsscheme.AddToScheme(scheme.Scheme)
+	decode := scheme.Codecs.UniversalDeserializer().Decode
+	obj, gvk, err := decode(content, nil, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode values")
+	}
+
+	if gvk.Group != "kots.io" || gvk.Version != "v1beta1" || gvk.Kind != "ConfigValues" {
+		return nil, errors.New("not a configvalues object")
+	}
+
+	return obj.(*kotsv1beta1.ConfigValues), nil
+}
+
+// TryParseConfig returns the Config in content, or nil if content is not a
+// kots.io/v1beta1 Config object
+func TryParseConfig(content []byte) *kotsv1beta1.Config {
+	kotsscheme.AddToScheme(scheme.Scheme)
+	decode := scheme.Codecs.UniversalDeserializer().Decode
+	obj, gvk, err := decode(content, nil, nil)
+	if err != nil {
+		return nil
+	}
+
+	if gvk.Group == "kots.io" && gvk.Version == "v1beta1" && gvk.Kind == "Config" {
+		return obj.(*kotsv1beta1.Config)
+	}
+
+	return nil
+}
+
+// TemplateContext returns the config values as the template context of a config context
+func TemplateContext(values *kotsv1beta1.ConfigValues) map[string]interface{} {
+	ctx := map[string]interface{}{}
+	for k, v := range values.Spec.Values {
+		ctx[k] = v
+	}
+	for k, v := range values.Spec.MultiValues {
+		ctx[k] = v
+	}
+
+	return ctx
+}
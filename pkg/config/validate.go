@@ -0,0 +1,136 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
+)
+
+// ValidationError is a problem with the value of a single config item
+type ValidationError struct {
+	Group   string
+	Item    string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("%s/%s: %s", e.Group, e.Item, e.Message)
+}
+
+// ValidationErrors are all of the problems found in the config values
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	lines := []string{"config values are invalid:"}
+	for _, validationError := range e {
+		lines = append(lines, fmt.Sprintf("  - %s", validationError.Error()))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// ValidateConfig checks the values of the config items against their types, whether they
+// are required and their validation rules. Items that have no value in itemValues are not
+// enabled, and are not checked. The errors are nil when the config values are valid
+func ValidateConfig(configGroups []kotsv1beta1.ConfigGroup, itemValues map[string]interface{}) ValidationErrors {
+	var validationErrors ValidationErrors
+	for _, configGroup := range configGroups {
+		for _, configItem := range configGroup.Items {
+			v, ok := itemValues[configItem.Name]
+			if !ok {
+				continue
+			}
+
+			values, ok := v.([]string)
+			if !ok {
+				if message := validateConfigItem(configItem, fmt.Sprintf("%v", v)); message != "" {
+					validationErrors = append(validationErrors, ValidationError{
+						Group:   configGroup.Name,
+						Item:    configItem.Name,
+						Message: message,
+					})
+				}
+				continue
+			}
+
+			// items with multiple values need at least one value when they are required,
+			// and each of the values is checked
+			if len(values) == 0 && configItem.Required {
+				validationErrors = append(validationErrors, ValidationError{
+					Group:   configGroup.Name,
+					Item:    configItem.Name,
+					Message: "a value is required",
+				})
+			}
+			for i, value := range values {
+				if message := validateConfigItem(configItem, value); message != "" {
+					validationErrors = append(validationErrors, ValidationError{
+						Group:   configGroup.Name,
+						Item:    fmt.Sprintf("%s[%d]", configItem.Name, i),
+						Message: message,
+					})
+				}
+			}
+		}
+	}
+
+	return validationErrors
+}
+
+// validateConfigItem returns a message describing what is wrong with the value, or an
+// empty string when the value is valid
+func validateConfigItem(configItem kotsv1beta1.ConfigItem, value string) string {
+	// these types are for display and have no value
+	if configItem.Type == "label" || configItem.Type == "heading" {
+		return ""
+	}
+
+	if strings.TrimSpace(value) == "" {
+		if configItem.Required {
+			return "a value is required"
+		}
+		return ""
+	}
+
+	switch configItem.Type {
+	case "bool":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Sprintf("%q is not a bool", value)
+		}
+	case "int":
+		if _, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64); err != nil {
+			return fmt.Sprintf("%q is not an int", value)
+		}
+	case "select_one":
+		if len(configItem.Items) == 0 {
+			break
+		}
+		options := []string{}
+		for _, childItem := range configItem.Items {
+			if childItem.Name == value {
+				return ""
+			}
+			options = append(options, childItem.Name)
+		}
+		return fmt.Sprintf("%q is not one of %s", value, strings.Join(options, ", "))
+	}
+
+	if configItem.Validation != nil && configItem.Validation.Regex != nil {
+		regex := configItem.Validation.Regex
+		re, err := regexp.Compile(regex.Pattern)
+		if err != nil {
+			return fmt.Sprintf("invalid validation pattern %q: %s", regex.Pattern, err.Error())
+		}
+		if !re.MatchString(value) {
+			if regex.Message != "" {
+				return regex.Message
+			}
+			return fmt.Sprintf("%q does not match %q", value, regex.Pattern)
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"testing"
+
+	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateConfig(t *testing.T) {
+	configGroups := []kotsv1beta1.ConfigGroup{
+		{
+			Name: "database",
+			Items: []kotsv1beta1.ConfigItem{
+				{
+					Name: "database_heading",
+					Type: "heading",
+				},
+				{
+					Name: "postgres_type",
+					Type: "select_one",
+					Items: []kotsv1beta1.ConfigChildItem{
+						{Name: "embedded"},
+						{Name: "external"},
+					},
+				},
+				{
+					Name:     "hostname",
+					Type:     "text",
+					Required: true,
+					Validation: &kotsv1beta1.ConfigItemValidation{
+						Regex: &kotsv1beta1.RegexValidator{
+							Pattern: `^[a-z0-9.-]+$`,
+							Message: "must be a valid hostname",
+						},
+					},
+				},
+				{
+					Name: "port",
+					Type: "int",
+				},
+				{
+					Name: "enable_tls",
+					Type: "bool",
+				},
+				{
+					Name: "username",
+					Type: "text",
+					Validation: &kotsv1beta1.ConfigItemValidation{
+						Regex: &kotsv1beta1.RegexValidator{
+							Pattern: `^[a-z]+$`,
+						},
+					},
+				},
+				{
+					Name:     "replica_ports",
+					Type:     "int",
+					Multiple: true,
+					Required: true,
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		itemValues map[string]interface{}
+		expected   ValidationErrors
+	}{
+		{
+			name: "valid",
+			itemValues: map[string]interface{}{
+				"database_heading": "",
+				"postgres_type":    "external",
+				"hostname":         "db.example.com",
+				"port":             "5432",
+				"enable_tls":       "1",
+				"username":         "",
+			},
+		},
+		{
+			name: "required items that are not enabled are not checked",
+			itemValues: map[string]interface{}{
+				"postgres_type": "embedded",
+			},
+		},
+		{
+			name: "invalid",
+			itemValues: map[string]interface{}{
+				"postgres_type": "sqlite",
+				"hostname":      " ",
+				"port":          "fifty",
+				"enable_tls":    "maybe",
+				"username":      "Admin",
+			},
+			expected: ValidationErrors{
+				{Group: "database", Item: "postgres_type", Message: `"sqlite" is not one of embedded, external`},
+				{Group: "database", Item: "hostname", Message: "a value is required"},
+				{Group: "database", Item: "port", Message: `"fifty" is not an int`},
+				{Group: "database", Item: "enable_tls", Message: `"maybe" is not a bool`},
+				{Group: "database", Item: "username", Message: `"Admin" does not match "^[a-z]+$"`},
+			},
+		},
+		{
+			name: "multiple values",
+			itemValues: map[string]interface{}{
+				"replica_ports": []string{"5432", "fifty"},
+			},
+			expected: ValidationErrors{
+				{Group: "database", Item: "replica_ports[1]", Message: `"fifty" is not an int`},
+			},
+		},
+		{
+			name: "required multiple values",
+			itemValues: map[string]interface{}{
+				"replica_ports": []string{},
+			},
+			expected: ValidationErrors{
+				{Group: "database", Item: "replica_ports", Message: "a value is required"},
+			},
+		},
+		{
+			name: "regex message",
+			itemValues: map[string]interface{}{
+				"hostname": "not a hostname",
+			},
+			expected: ValidationErrors{
+				{Group: "database", Item: "hostname", Message: "must be a valid hostname"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, ValidateConfig(configGroups, test.itemValues))
+		})
+	}
+}
+
+func TestValidationErrorsError(t *testing.T) {
+	validationErrors := ValidationErrors{
+		{Group: "database", Item: "hostname", Message: "a value is required"},
+		{Group: "database", Item: "port", Message: `"fifty" is not an int`},
+	}
+
+	assert.Equal(t, `config values are invalid:
+  - database/hostname: a value is required
+  - database/port: "fifty" is not an int`, validationErrors.Error())
+}
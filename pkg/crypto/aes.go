@@ -0,0 +1,99 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// EncryptedPrefix marks values that are encrypted, so that they are not encrypted twice
+// and can be decrypted without knowing where they came from
+const EncryptedPrefix = "kots.io/encrypted:"
+
+// IsEncrypted returns true for values that were encrypted with Encrypt
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, EncryptedPrefix)
+}
+
+// Encrypt encrypts the plaintext with the installation encryption key. Every value gets a
+// random nonce, which is stored before the ciphertext
+func Encrypt(encryptionKey string, plaintext string) (string, error) {
+	gcm, err := newGCM(encryptionKey)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create cipher")
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", errors.Wrap(err, "failed to read nonce")
+	}
+
+	ciphertext := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+
+	return EncryptedPrefix + base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// Decrypt decrypts a value that was encrypted with Encrypt. Values that are not
+// encrypted are returned as they are
+func Decrypt(encryptionKey string, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, EncryptedPrefix))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to decode value")
+	}
+
+	gcm, err := newGCM(encryptionKey)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create cipher")
+	}
+
+	if len(ciphertext) < gcm.NonceSize() {
+		return "", errors.New("encrypted value is too short")
+	}
+
+	plaintext, err := gcm.Open(nil, ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to decrypt value")
+	}
+
+	return string(plaintext), nil
+}
+
+// newGCM creates the cipher from an installation encryption key, which is the base64
+// encoded aes key followed by a nonce. The stored nonce is not used, because reusing a
+// nonce for more than one value is not safe
+func newGCM(encryptionKey string) (cipher.AEAD, error) {
+	if encryptionKey == "" {
+		return nil, errors.New("there is no encryption key")
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(encryptionKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode encryption key")
+	}
+
+	// the nonce that is stored with the key is the standard gcm nonce size
+	const storedNonceSize = 12
+	if len(decoded) <= storedNonceSize {
+		return nil, errors.New("encryption key is too short")
+	}
+
+	block, err := aes.NewCipher(decoded[:len(decoded)-storedNonceSize])
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create new cipher")
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to wrap cipher gcm")
+	}
+
+	return gcm, nil
+}
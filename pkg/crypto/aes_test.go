@@ -0,0 +1,42 @@
+package crypto
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testEncryptionKey = "tUg2e934J7ethZNX2WDaiUNd0E1Z2iJ3SyQEt81zzxIiM48K"
+
+func TestEncryptDecrypt(t *testing.T) {
+	req := require.New(t)
+
+	encrypted, err := Encrypt(testEncryptionKey, "hunter2")
+	req.NoError(err)
+	assert.True(t, IsEncrypted(encrypted))
+	assert.NotContains(t, encrypted, "hunter2")
+
+	// every value has its own nonce
+	encryptedAgain, err := Encrypt(testEncryptionKey, "hunter2")
+	req.NoError(err)
+	assert.NotEqual(t, encrypted, encryptedAgain)
+
+	decrypted, err := Decrypt(testEncryptionKey, encrypted)
+	req.NoError(err)
+	assert.Equal(t, "hunter2", decrypted)
+
+	// values that are not encrypted are not changed
+	decrypted, err = Decrypt(testEncryptionKey, "hunter2")
+	req.NoError(err)
+	assert.Equal(t, "hunter2", decrypted)
+
+	// a value that was changed does not decrypt
+	tampered := encrypted[:len(encrypted)-4] + strings.Repeat("A", 4)
+	_, err = Decrypt(testEncryptionKey, tampered)
+	req.Error(err)
+
+	_, err = Decrypt("", encrypted)
+	req.Error(err)
+}
@@ -0,0 +1,121 @@
+package diff
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/replicatedhq/kots/pkg/k8sutil"
+)
+
+// Change is how a resource changed
+type Change string
+
+const (
+	Added   Change = "added"
+	Removed Change = "removed"
+	Changed Change = "changed"
+)
+
+// ResourceDiff is the change to a single resource, with a unified diff of its yaml
+type ResourceDiff struct {
+	ID     k8sutil.ResourceID
+	Change Change
+	Diff   string
+}
+
+// DiffResources compares the resources in two multi document yaml streams by their id,
+// and returns the resources that were added, removed or changed, sorted by id
+func DiffResources(from []byte, to []byte) ([]ResourceDiff, error) {
+	fromResources, err := resourcesByID(from)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse from resources")
+	}
+	toResources, err := resourcesByID(to)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse to resources")
+	}
+
+	ids := map[k8sutil.ResourceID]bool{}
+	for id := range fromResources {
+		ids[id] = true
+	}
+	for id := range toResources {
+		ids[id] = true
+	}
+	sortedIDs := []k8sutil.ResourceID{}
+	for id := range ids {
+		sortedIDs = append(sortedIDs, id)
+	}
+	sort.Slice(sortedIDs, func(i, j int) bool {
+		return sortedIDs[i].String() < sortedIDs[j].String()
+	})
+
+	resourceDiffs := []ResourceDiff{}
+	for _, id := range sortedIDs {
+		fromContent, toContent := fromResources[id], toResources[id]
+		if string(fromContent) == string(toContent) {
+			continue
+		}
+
+		change := Changed
+		fromFile, toFile := "a/"+id.String(), "b/"+id.String()
+		if fromContent == nil {
+			change = Added
+			fromFile = "/dev/null"
+		}
+		if toContent == nil {
+			change = Removed
+			toFile = "/dev/null"
+		}
+
+		unifiedDiff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        splitLines(fromContent),
+			B:        splitLines(toContent),
+			FromFile: fromFile,
+			ToFile:   toFile,
+			Context:  3,
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to diff %s", id)
+		}
+
+		resourceDiffs = append(resourceDiffs, ResourceDiff{
+			ID:     id,
+			Change: change,
+			Diff:   unifiedDiff,
+		})
+	}
+
+	return resourceDiffs, nil
+}
+
+// splitLines splits content into lines that keep their line endings. Unlike
+// difflib.SplitLines, empty content has no lines
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return []string{}
+	}
+
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+func resourcesByID(content []byte) (map[k8sutil.ResourceID][]byte, error) {
+	resources, err := k8sutil.ParseResources(content)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := map[k8sutil.ResourceID][]byte{}
+	for _, resource := range resources {
+		byID[resource.ID] = resource.Content
+	}
+
+	return byID, nil
+}
@@ -0,0 +1,102 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/replicatedhq/kots/pkg/k8sutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffResources(t *testing.T) {
+	req := require.New(t)
+
+	from := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: unchanged
+data:
+  a: b
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  version: "1"
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: removed
+`
+	// the order of the resources doesn't matter
+	to := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  version: "2"
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: unchanged
+data:
+  a: b
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: added
+  namespace: app
+`
+
+	resourceDiffs, err := DiffResources([]byte(from), []byte(to))
+	req.NoError(err)
+
+	assert.Equal(t, []ResourceDiff{
+		{
+			ID:     k8sutil.ResourceID{APIVersion: "v1", Kind: "ConfigMap", Name: "config"},
+			Change: Changed,
+			Diff: `--- a/v1/ConfigMap/config
++++ b/v1/ConfigMap/config
+@@ -3,4 +3,4 @@
+ metadata:
+   name: config
+ data:
+-  version: "1"
++  version: "2"
+`,
+		},
+		{
+			ID:     k8sutil.ResourceID{APIVersion: "v1", Kind: "Secret", Name: "removed"},
+			Change: Removed,
+			Diff: `--- a/v1/Secret/removed
++++ /dev/null
+@@ -1,4 +0,0 @@
+-apiVersion: v1
+-kind: Secret
+-metadata:
+-  name: removed
+`,
+		},
+		{
+			ID:     k8sutil.ResourceID{APIVersion: "v1", Kind: "Service", Namespace: "app", Name: "added"},
+			Change: Added,
+			Diff: `--- /dev/null
++++ b/v1/Service/app/added
+@@ -0,0 +1,5 @@
++apiVersion: v1
++kind: Service
++metadata:
++  name: added
++  namespace: app
+`,
+		},
+	}, resourceDiffs)
+
+	resourceDiffs, err = DiffResources([]byte(from), []byte(from))
+	req.NoError(err)
+	assert.Empty(t, resourceDiffs)
+}
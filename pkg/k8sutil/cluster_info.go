@@ -0,0 +1,81 @@
+package k8sutil
+
+import (
+	"io/ioutil"
+	"sort"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+// ClusterInfo is what is known about the cluster that an application is rendered for. It
+// is read from the cluster, or from a file when rendering offline
+type ClusterInfo struct {
+	KubeVersion    string   `json:"kubeVersion,omitempty"`
+	APIVersions    []string `json:"apiVersions,omitempty"`
+	NodeCount      int      `json:"nodeCount,omitempty"`
+	StorageClasses []string `json:"storageClasses,omitempty"`
+	// Secrets are only read from a cluster info file. Secrets in a cluster are looked up
+	// when they are used
+	Secrets []ClusterInfoSecret `json:"secrets,omitempty"`
+}
+
+// ClusterInfoSecret is a secret in a cluster info file, with data that is not encoded
+type ClusterInfoSecret struct {
+	Namespace string            `json:"namespace"`
+	Name      string            `json:"name"`
+	Data      map[string]string `json:"data,omitempty"`
+}
+
+// GetClusterInfo reads the cluster info from the cluster
+func GetClusterInfo(clientset kubernetes.Interface) (*ClusterInfo, error) {
+	serverVersion, err := clientset.Discovery().ServerVersion()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get server version")
+	}
+
+	groups, err := clientset.Discovery().ServerGroups()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get server groups")
+	}
+
+	nodes, err := clientset.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list nodes")
+	}
+
+	storageClasses, err := clientset.StorageV1().StorageClasses().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list storage classes")
+	}
+
+	clusterInfo := &ClusterInfo{
+		KubeVersion:    serverVersion.GitVersion,
+		APIVersions:    metav1.ExtractGroupVersions(groups),
+		NodeCount:      len(nodes.Items),
+		StorageClasses: []string{},
+	}
+	for _, storageClass := range storageClasses.Items {
+		clusterInfo.StorageClasses = append(clusterInfo.StorageClasses, storageClass.Name)
+	}
+	sort.Strings(clusterInfo.StorageClasses)
+
+	return clusterInfo, nil
+}
+
+// ReadClusterInfoFile reads the cluster info from a yaml file
+func ReadClusterInfoFile(filename string) (*ClusterInfo, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read cluster info file")
+	}
+
+	clusterInfo := ClusterInfo{}
+	if err := yaml.Unmarshal(b, &clusterInfo); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal cluster info")
+	}
+
+	return &clusterInfo, nil
+}
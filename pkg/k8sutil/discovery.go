@@ -0,0 +1,34 @@
+package k8sutil
+
+import (
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// GetClusterCapabilities returns the kubernetes version and the api versions
+// that are available in the cluster from the kubeconfig
+func GetClusterCapabilities(kubeconfig string) (string, []string, error) {
+	cfg, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to build config")
+	}
+
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(cfg)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to create discovery client")
+	}
+
+	serverVersion, err := discoveryClient.ServerVersion()
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to get server version")
+	}
+
+	groups, err := discoveryClient.ServerGroups()
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to get server groups")
+	}
+
+	return serverVersion.GitVersion, metav1.ExtractGroupVersions(groups), nil
+}
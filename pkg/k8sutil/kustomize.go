@@ -0,0 +1,47 @@
+package k8sutil
+
+import (
+	"github.com/pkg/errors"
+	"sigs.k8s.io/kustomize/v3/k8sdeps/kunstruct"
+	"sigs.k8s.io/kustomize/v3/k8sdeps/transformer"
+	"sigs.k8s.io/kustomize/v3/k8sdeps/validator"
+	"sigs.k8s.io/kustomize/v3/pkg/fs"
+	"sigs.k8s.io/kustomize/v3/pkg/loader"
+	"sigs.k8s.io/kustomize/v3/pkg/plugins"
+	"sigs.k8s.io/kustomize/v3/pkg/resmap"
+	"sigs.k8s.io/kustomize/v3/pkg/resource"
+	"sigs.k8s.io/kustomize/v3/pkg/target"
+)
+
+// BuildKustomization runs kustomize build on the kustomization in dir, and returns the
+// resources as a multi document yaml stream
+func BuildKustomization(dir string) ([]byte, error) {
+	fSys := fs.MakeRealFS()
+	unstructuredFactory := kunstruct.NewKunstructuredFactoryImpl()
+	patchFactory := transformer.NewFactoryImpl()
+	resmapFactory := resmap.NewFactory(resource.NewFactory(unstructuredFactory), patchFactory)
+	pluginLoader := plugins.NewLoader(plugins.DefaultPluginConfig(), resmapFactory)
+
+	ldr, err := loader.NewLoader(loader.RestrictionRootOnly, validator.NewKustValidator(), dir, fSys)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create loader")
+	}
+	defer ldr.Cleanup()
+
+	kustTarget, err := target.NewKustTarget(ldr, resmapFactory, patchFactory, pluginLoader)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create kustomize target")
+	}
+
+	resMap, err := kustTarget.MakeCustomizedResMap()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build kustomization")
+	}
+
+	content, err := resMap.AsYaml()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal resources")
+	}
+
+	return content, nil
+}
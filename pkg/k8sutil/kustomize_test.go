@@ -0,0 +1,42 @@
+package k8sutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildKustomization(t *testing.T) {
+	req := require.New(t)
+
+	dir, err := ioutil.TempDir("", "kots-kustomize")
+	req.NoError(err)
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"base/kustomization.yaml":    "resources:\n- config.yaml\n- secret.yaml\n",
+		"base/config.yaml":           "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\ndata:\n  a: b\n",
+		"base/secret.yaml":           "apiVersion: v1\nkind: Secret\nmetadata:\n  name: secret\n",
+		"overlay/kustomization.yaml": "bases:\n- ../base\nnamespace: app\n",
+	}
+	for name, content := range files {
+		req.NoError(os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755))
+		req.NoError(ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	content, err := BuildKustomization(filepath.Join(dir, "overlay"))
+	req.NoError(err)
+
+	resources, err := ParseResources(content)
+	req.NoError(err)
+	req.Len(resources, 2)
+	assert.Equal(t, "v1/ConfigMap/app/config", resources[0].ID.String())
+	assert.Equal(t, "v1/Secret/app/secret", resources[1].ID.String())
+
+	_, err = BuildKustomization(filepath.Join(dir, "missing"))
+	req.Error(err)
+}
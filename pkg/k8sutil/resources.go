@@ -0,0 +1,112 @@
+package k8sutil
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/util"
+	"gopkg.in/yaml.v2"
+)
+
+// ResourceID identifies a resource by its group, version, kind, namespace and name
+type ResourceID struct {
+	APIVersion string
+	Kind       string
+	Namespace  string
+	Name       string
+}
+
+// String returns the id as apiVersion/kind/namespace/name, without the namespace for
+// resources that don't have one
+func (id ResourceID) String() string {
+	parts := []string{id.APIVersion, id.Kind}
+	if id.Namespace != "" {
+		parts = append(parts, id.Namespace)
+	}
+	parts = append(parts, id.Name)
+
+	return strings.Join(parts, "/")
+}
+
+// Resource is a single document of a multi document yaml stream
+type Resource struct {
+	ID      ResourceID
+	Content []byte
+}
+
+type resourceMetadata struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name      string `yaml:"name"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"metadata"`
+}
+
+// ParseResources splits a multi document yaml stream into its resources. Empty documents
+// are skipped, and documents that are not kubernetes resources are an error
+func ParseResources(content []byte) ([]Resource, error) {
+	resources := []Resource{}
+	for _, doc := range util.SplitYAML(content) {
+		metadata := resourceMetadata{}
+		if err := yaml.Unmarshal(doc, &metadata); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal resource")
+		}
+		if metadata.APIVersion == "" || metadata.Kind == "" || metadata.Metadata.Name == "" {
+			return nil, errors.Errorf("document is not a resource: %s", strings.SplitN(string(doc), "\n", 2)[0])
+		}
+
+		if !bytes.HasSuffix(doc, []byte("\n")) {
+			doc = append(append([]byte{}, doc...), '\n')
+		}
+
+		resources = append(resources, Resource{
+			ID: ResourceID{
+				APIVersion: metadata.APIVersion,
+				Kind:       metadata.Kind,
+				Namespace:  metadata.Metadata.Namespace,
+				Name:       metadata.Metadata.Name,
+			},
+			Content: doc,
+		})
+	}
+
+	return resources, nil
+}
+
+// Filename returns a file name for the resource, from its namespace, kind and name
+func (id ResourceID) Filename() string {
+	parts := []string{}
+	if id.Namespace != "" {
+		parts = append(parts, id.Namespace)
+	}
+	parts = append(parts, id.Kind, id.Name)
+
+	return strings.ToLower(strings.Join(parts, "_")) + ".yaml"
+}
+
+// WriteResources writes each resource to its own file in dir
+func WriteResources(dir string, resources []Resource) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.Wrap(err, "failed to create dir")
+	}
+
+	written := map[string]ResourceID{}
+	for _, resource := range resources {
+		if other, ok := written[resource.ID.Filename()]; ok {
+			return errors.Errorf("%s and %s have the same file name", other, resource.ID)
+		}
+		written[resource.ID.Filename()] = resource.ID
+
+		filename := filepath.Join(dir, resource.ID.Filename())
+		if err := ioutil.WriteFile(filename, resource.Content, 0644); err != nil {
+			return errors.Wrapf(err, "failed to write %s", resource.ID)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package k8sutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseResources(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected []Resource
+		wantErr  bool
+	}{
+		{
+			name:     "empty",
+			content:  "",
+			expected: []Resource{},
+		},
+		{
+			name: "multiple documents",
+			content: `---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: app
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: app
+---
+`,
+			expected: []Resource{
+				{
+					ID:      ResourceID{APIVersion: "v1", Kind: "Namespace", Name: "app"},
+					Content: []byte("apiVersion: v1\nkind: Namespace\nmetadata:\n  name: app\n"),
+				},
+				{
+					ID:      ResourceID{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "app", Name: "web"},
+					Content: []byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n  namespace: app\n"),
+				},
+			},
+		},
+		{
+			name:    "not a resource",
+			content: "a: b\n",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := ParseResources([]byte(test.content))
+			if test.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestResourceIDString(t *testing.T) {
+	assert.Equal(t, "apps/v1/Deployment/app/web", ResourceID{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "app", Name: "web"}.String())
+	assert.Equal(t, "v1/Namespace/app", ResourceID{APIVersion: "v1", Kind: "Namespace", Name: "app"}.String())
+}
+
+func TestWriteResources(t *testing.T) {
+	req := require.New(t)
+
+	dir, err := ioutil.TempDir("", "kots-resources")
+	req.NoError(err)
+	defer os.RemoveAll(dir)
+
+	resources := []Resource{
+		{
+			ID:      ResourceID{APIVersion: "v1", Kind: "Namespace", Name: "app"},
+			Content: []byte("apiVersion: v1\nkind: Namespace\nmetadata:\n  name: app\n"),
+		},
+		{
+			ID:      ResourceID{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "app", Name: "web"},
+			Content: []byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n  namespace: app\n"),
+		},
+	}
+	req.NoError(WriteResources(filepath.Join(dir, "manifests"), resources))
+
+	for _, resource := range resources {
+		content, err := ioutil.ReadFile(filepath.Join(dir, "manifests", resource.ID.Filename()))
+		req.NoError(err)
+		assert.Equal(t, string(resource.Content), string(content))
+	}
+	assert.Equal(t, "app_deployment_web.yaml", resources[1].ID.Filename())
+
+	// resources that would overwrite each other are an error
+	resources = append(resources, Resource{
+		ID:      ResourceID{APIVersion: "extensions/v1beta1", Kind: "Deployment", Namespace: "app", Name: "web"},
+		Content: []byte("apiVersion: extensions/v1beta1\nkind: Deployment\nmetadata:\n  name: web\n  namespace: app\n"),
+	})
+	req.Error(WriteResources(filepath.Join(dir, "manifests"), resources))
+}
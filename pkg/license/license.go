@@ -0,0 +1,147 @@
+package license
+
+import (
+	"crypto"
+	"crypto/md5"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"reflect"
+
+	"github.com/pkg/errors"
+	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
+	kotsscheme "github.com/replicatedhq/kots/kotskinds/client/kotsclientset/scheme"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// ErrInvalidSignature is returned for licenses that were not signed, or that were
+// changed after they were signed
+var ErrInvalidSignature = errors.New("license signature is invalid")
+
+// outerSignature is the content of the signature field of a license. The license data
+// is the license document exactly as it was signed
+type outerSignature struct {
+	LicenseData    []byte `json:"licenseData"`
+	InnerSignature []byte `json:"innerSignature"`
+}
+
+// innerSignature signs the license data with the app's key, and the app's key with
+// one of the global keys
+type innerSignature struct {
+	LicenseSignature []byte `json:"licenseSignature"`
+	PublicKey        string `json:"publicKey"`
+	KeySignature     []byte `json:"keySignature"`
+}
+
+type keySignature struct {
+	Signature   []byte `json:"signature"`
+	GlobalKeyID string `json:"globalKeyId"`
+}
+
+// ParseLicense decodes the license and verifies its signature. The returned license is
+// the signed license data, so all of the fields of its spec can be trusted
+func ParseLicense(content []byte) (*kotsv1beta1.License, error) {
+	license, err := decodeLicense(content)
+	if err != nil {
+		return nil, err
+	}
+
+	return VerifySignature(license)
+}
+
+// VerifySignature checks the signature of the license, and that the license matches the
+// data that was signed. It returns the license decoded from the signed data
+func VerifySignature(license *kotsv1beta1.License) (*kotsv1beta1.License, error) {
+	outer := outerSignature{}
+	if err := json.Unmarshal(license.Spec.Signature, &outer); err != nil {
+		return nil, errors.Wrap(ErrInvalidSignature, "failed to unmarshal license signature")
+	}
+	inner := innerSignature{}
+	if err := json.Unmarshal(outer.InnerSignature, &inner); err != nil {
+		return nil, errors.Wrap(ErrInvalidSignature, "failed to unmarshal inner signature")
+	}
+	keySig := keySignature{}
+	if err := json.Unmarshal(inner.KeySignature, &keySig); err != nil {
+		return nil, errors.Wrap(ErrInvalidSignature, "failed to unmarshal key signature")
+	}
+
+	if len(publicKeys) == 0 {
+		return nil, errors.Wrapf(ErrInvalidSignature, "unknown global key %q, this build has no trusted global keys", keySig.GlobalKeyID)
+	}
+	globalKeyPEM, ok := publicKeys[keySig.GlobalKeyID]
+	if !ok {
+		return nil, errors.Wrapf(ErrInvalidSignature, "unknown global key %q", keySig.GlobalKeyID)
+	}
+	globalKey, err := parsePublicKey(globalKeyPEM)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse global key")
+	}
+	if err := verify(globalKey, []byte(inner.PublicKey), keySig.Signature); err != nil {
+		return nil, errors.Wrap(ErrInvalidSignature, "app key is not signed by the global key")
+	}
+
+	appKey, err := parsePublicKey([]byte(inner.PublicKey))
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidSignature, "failed to parse app key")
+	}
+	if err := verify(appKey, outer.LicenseData, inner.LicenseSignature); err != nil {
+		return nil, errors.Wrap(ErrInvalidSignature, "license data is not signed by the app key")
+	}
+
+	verified, err := decodeLicense(outer.LicenseData)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode license data")
+	}
+
+	// the signature of the signed license data is not known when it is signed
+	spec := license.Spec.DeepCopy()
+	spec.Signature = verified.Spec.Signature
+	if !reflect.DeepEqual(*spec, verified.Spec) {
+		return nil, errors.Wrap(ErrInvalidSignature, "license does not match the signed license data")
+	}
+
+	verified.Spec.Signature = license.Spec.Signature
+	return verified, nil
+}
+
+func decodeLicense(content []byte) (*kotsv1beta1.License, error) {
+	kotsscheme.AddToScheme(scheme.Scheme)
+	decode := scheme.Codecs.UniversalDeserializer().Decode
+	obj, gvk, err := decode(content, nil, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to decode license file")
+	}
+
+	if gvk.Group != "kots.io" || gvk.Version != "v1beta1" || gvk.Kind != "License" {
+		return nil, errors.New("not an application license")
+	}
+
+	return obj.(*kotsv1beta1.License), nil
+}
+
+// verify checks an rsa-pss signature over the md5 hash of the message
+func verify(publicKey *rsa.PublicKey, message []byte, signature []byte) error {
+	hashed := md5.Sum(message)
+	opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto}
+	return rsa.VerifyPSS(publicKey, crypto.MD5, hashed[:], signature, opts)
+}
+
+func parsePublicKey(pemData []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, errors.New("no pem block found")
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse pkix public key")
+	}
+
+	publicKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public key is not an rsa key")
+	}
+
+	return publicKey, nil
+}
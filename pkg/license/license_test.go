@@ -0,0 +1,209 @@
+package license
+
+import (
+	"crypto"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"testing"
+
+	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testGlobalKeyID = "test-global-key"
+
+// useTestKey trusts a new global key, until restore is called
+func useTestKey(t *testing.T) (globalKey *rsa.PrivateKey, restore func()) {
+	req := require.New(t)
+
+	globalKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	req.NoError(err)
+
+	publicKeys[testGlobalKeyID] = encodePublicKey(t, &globalKey.PublicKey)
+
+	return globalKey, func() {
+		delete(publicKeys, testGlobalKeyID)
+	}
+}
+
+// sign returns the signature field of a license with the license data, in the vendor's
+// signature format
+func sign(t *testing.T, globalKey *rsa.PrivateKey, licenseData string) string {
+	req := require.New(t)
+
+	appKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	req.NoError(err)
+	appPublicKey := encodePublicKey(t, &appKey.PublicKey)
+
+	keySig, err := json.Marshal(keySignature{
+		Signature:   signPSS(t, globalKey, appPublicKey),
+		GlobalKeyID: testGlobalKeyID,
+	})
+	req.NoError(err)
+
+	inner, err := json.Marshal(innerSignature{
+		LicenseSignature: signPSS(t, appKey, []byte(licenseData)),
+		PublicKey:        string(appPublicKey),
+		KeySignature:     keySig,
+	})
+	req.NoError(err)
+
+	outer, err := json.Marshal(outerSignature{
+		LicenseData:    []byte(licenseData),
+		InnerSignature: inner,
+	})
+	req.NoError(err)
+
+	return base64.StdEncoding.EncodeToString(outer)
+}
+
+func signPSS(t *testing.T, privateKey *rsa.PrivateKey, message []byte) []byte {
+	hashed := md5.Sum(message)
+	signature, err := rsa.SignPSS(rand.Reader, privateKey, crypto.MD5, hashed[:], nil)
+	require.NoError(t, err)
+	return signature
+}
+
+func encodePublicKey(t *testing.T, publicKey *rsa.PublicKey) []byte {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func TestParseLicense(t *testing.T) {
+	globalKey, restore := useTestKey(t)
+	defer restore()
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	licenseData := `apiVersion: kots.io/v1beta1
+kind: License
+metadata:
+  name: my-app
+spec:
+  appSlug: my-app
+  endpoint: https://replicated.app
+  licenseID: abc123
+  isAirgapSupported: true
+  entitlements:
+    seats:
+      title: Seats
+      value: 25
+`
+	signature := sign(t, globalKey, licenseData)
+
+	tests := []struct {
+		name        string
+		content     string
+		expectedErr string
+	}{
+		{
+			name:    "signed",
+			content: licenseData + "  signature: " + signature,
+		},
+		{
+			name: "tampered entitlement",
+			content: `apiVersion: kots.io/v1beta1
+kind: License
+metadata:
+  name: my-app
+spec:
+  appSlug: my-app
+  endpoint: https://replicated.app
+  licenseID: abc123
+  isAirgapSupported: true
+  entitlements:
+    seats:
+      title: Seats
+      value: 250
+  signature: ` + signature,
+			expectedErr: "license does not match the signed license data",
+		},
+		{
+			name:        "signed by an unknown global key",
+			content:     licenseData + "  signature: " + sign(t, otherKey, licenseData),
+			expectedErr: "app key is not signed by the global key",
+		},
+		{
+			name: "unsigned",
+			content: `apiVersion: kots.io/v1beta1
+kind: License
+metadata:
+  name: my-app
+spec:
+  appSlug: my-app
+  licenseID: abc123
+  signature: IA==`,
+			expectedErr: ErrInvalidSignature.Error(),
+		},
+		{
+			name: "not a license",
+			content: `apiVersion: kots.io/v1beta1
+kind: ConfigValues
+metadata:
+  name: my-app
+spec:
+  values: {}`,
+			expectedErr: "not an application license",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			license, err := ParseLicense([]byte(test.content))
+			if test.expectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "abc123", license.Spec.LicenseID)
+			assert.Equal(t, kotsv1beta1.EntitlementValue{Type: kotsv1beta1.EntitlementValueTypeInt, IntVal: 25}, license.Spec.Entitlements["seats"].Value)
+			assert.NotEmpty(t, license.Spec.Signature)
+		})
+	}
+}
+
+func TestVerifySignatureWithoutTestKey(t *testing.T) {
+	globalKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	licenseData := `apiVersion: kots.io/v1beta1
+kind: License
+metadata:
+  name: my-app
+spec:
+  appSlug: my-app
+  licenseID: abc123
+`
+	signature, err := base64.StdEncoding.DecodeString(sign(t, globalKey, licenseData))
+	require.NoError(t, err)
+
+	license := &kotsv1beta1.License{
+		Spec: kotsv1beta1.LicenseSpec{
+			AppSlug:   "my-app",
+			LicenseID: "abc123",
+			Signature: signature,
+		},
+	}
+
+	// a license that is signed by a key that is not trusted is not valid
+	_, err = VerifySignature(license)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown global key "test-global-key"`)
+}
+
+func TestPublicKeys(t *testing.T) {
+	for keyID, pemData := range publicKeys {
+		_, err := parsePublicKey(pemData)
+		assert.NoError(t, err, "global key %q", keyID)
+	}
+}
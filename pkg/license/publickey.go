@@ -0,0 +1,6 @@
+package license
+
+// publicKeys are the published global keys that sign the app keys, by the globalKeyId in
+// the key signature. Licenses that are signed with any other key are rejected. Every key
+// here must be a PEM encoded RSA public key
+var publicKeys = map[string][]byte{}
@@ -0,0 +1,132 @@
+package pull
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/otiai10/copy"
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/diff"
+	"github.com/replicatedhq/kots/pkg/k8sutil"
+	"github.com/replicatedhq/kots/pkg/upstream"
+)
+
+// DryRun is an app that was pulled into a copy of it in a temp dir, leaving the app
+// on disk as it was
+type DryRun struct {
+	// AppDir is the app on disk
+	AppDir string
+	// DryRunDir is the copy of the app that was pulled into
+	DryRunDir string
+
+	rootDir string
+}
+
+// KustomizationDiff is the change to the resources that kustomize builds for a kustomization
+// in the app, such as a downstream
+type KustomizationDiff struct {
+	Kustomization string
+	Resources     []diff.ResourceDiff
+}
+
+// DryRunPull pulls the application like Pull, into a copy of the app in a temp dir.
+// The copy should be removed with Remove
+func DryRunPull(upstreamURI string, pullOptions PullOptions) (*DryRun, error) {
+	if pullOptions.RewriteImages {
+		return nil, errors.New("images can't be rewritten in a dry run")
+	}
+
+	dryRun := &DryRun{}
+	pullOptions.dryRun = dryRun
+	if _, err := Pull(upstreamURI, pullOptions); err != nil {
+		dryRun.Remove()
+		return nil, err
+	}
+
+	return dryRun, nil
+}
+
+// prepare copies the app that the upstream would be pulled to into a temp dir, and
+// changes the options to pull into the copy
+func (d *DryRun) prepare(u *upstream.Upstream, pullOptions *PullOptions) error {
+	rootDir, err := ioutil.TempDir("", "kots-pull")
+	if err != nil {
+		return errors.Wrap(err, "failed to create temp dir")
+	}
+	d.rootDir = rootDir
+
+	d.AppDir = appDirFromOptions(u, *pullOptions)
+	pullOptions.RootDir = rootDir
+	d.DryRunDir = appDirFromOptions(u, *pullOptions)
+
+	if _, err := os.Stat(d.AppDir); os.IsNotExist(err) {
+		return nil
+	}
+	if err := copy.Copy(d.AppDir, d.DryRunDir); err != nil {
+		return errors.Wrap(err, "failed to copy app")
+	}
+
+	return nil
+}
+
+// Remove removes the copy of the app
+func (d *DryRun) Remove() error {
+	if d.rootDir == "" {
+		return nil
+	}
+
+	return os.RemoveAll(d.rootDir)
+}
+
+// Diff compares the resources that kustomize builds for the app on disk and the dry run.
+// Every downstream of the dry run is compared, or the midstream if there are none
+func (d *DryRun) Diff() ([]KustomizationDiff, error) {
+	kustomizations := []string{}
+	downstreams, err := ioutil.ReadDir(filepath.Join(d.DryRunDir, "overlays", "downstreams"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, "failed to read downstreams")
+	}
+	for _, downstream := range downstreams {
+		if downstream.IsDir() {
+			kustomizations = append(kustomizations, filepath.Join("overlays", "downstreams", downstream.Name()))
+		}
+	}
+	if len(kustomizations) == 0 {
+		kustomizations = append(kustomizations, filepath.Join("overlays", "midstream"))
+	}
+
+	kustomizationDiffs := []KustomizationDiff{}
+	for _, kustomization := range kustomizations {
+		current, err := buildKustomizationIfExists(filepath.Join(d.AppDir, kustomization))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to build %s in %s", kustomization, d.AppDir)
+		}
+		updated, err := buildKustomizationIfExists(filepath.Join(d.DryRunDir, kustomization))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to build %s in dry run", kustomization)
+		}
+
+		resourceDiffs, err := diff.DiffResources(current, updated)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to diff %s", kustomization)
+		}
+
+		kustomizationDiffs = append(kustomizationDiffs, KustomizationDiff{
+			Kustomization: kustomization,
+			Resources:     resourceDiffs,
+		})
+	}
+
+	return kustomizationDiffs, nil
+}
+
+// buildKustomizationIfExists builds the kustomization in dir, which has no resources
+// if it doesn't exist
+func buildKustomizationIfExists(dir string) ([]byte, error) {
+	if _, err := os.Stat(filepath.Join(dir, "kustomization.yaml")); os.IsNotExist(err) {
+		return []byte{}, nil
+	}
+
+	return k8sutil.BuildKustomization(dir)
+}
@@ -0,0 +1,73 @@
+package pull
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/replicatedhq/kots/pkg/diff"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestApp(t *testing.T, appDir string, files map[string]string) {
+	for name, content := range files {
+		filename := filepath.Join(appDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(filename), 0755))
+		require.NoError(t, ioutil.WriteFile(filename, []byte(content), 0644))
+	}
+}
+
+func TestDryRunDiff(t *testing.T) {
+	req := require.New(t)
+
+	rootDir, err := ioutil.TempDir("", "kots-dry-run")
+	req.NoError(err)
+	defer os.RemoveAll(rootDir)
+
+	downstream := `bases:
+- ../../midstream
+namespace: app
+`
+	midstream := `bases:
+- ../../base
+`
+
+	dryRun := DryRun{
+		AppDir:    filepath.Join(rootDir, "app"),
+		DryRunDir: filepath.Join(rootDir, "dry-run"),
+	}
+	writeTestApp(t, dryRun.AppDir, map[string]string{
+		"base/kustomization.yaml":                              "resources:\n- config.yaml\n",
+		"base/config.yaml":                                     "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\ndata:\n  version: \"1\"\n",
+		"overlays/midstream/kustomization.yaml":                midstream,
+		"overlays/downstreams/this-cluster/kustomization.yaml": downstream,
+	})
+	writeTestApp(t, dryRun.DryRunDir, map[string]string{
+		"base/kustomization.yaml":                              "resources:\n- config.yaml\n- service.yaml\n",
+		"base/config.yaml":                                     "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\ndata:\n  version: \"2\"\n",
+		"base/service.yaml":                                    "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n",
+		"overlays/midstream/kustomization.yaml":                midstream,
+		"overlays/downstreams/this-cluster/kustomization.yaml": downstream,
+		"overlays/downstreams/new-cluster/kustomization.yaml":  downstream,
+	})
+
+	kustomizationDiffs, err := dryRun.Diff()
+	req.NoError(err)
+	req.Len(kustomizationDiffs, 2)
+
+	// a new downstream adds all of the resources
+	assert.Equal(t, filepath.Join("overlays", "downstreams", "new-cluster"), kustomizationDiffs[0].Kustomization)
+	req.Len(kustomizationDiffs[0].Resources, 2)
+	assert.Equal(t, diff.Added, kustomizationDiffs[0].Resources[0].Change)
+	assert.Equal(t, diff.Added, kustomizationDiffs[0].Resources[1].Change)
+
+	assert.Equal(t, filepath.Join("overlays", "downstreams", "this-cluster"), kustomizationDiffs[1].Kustomization)
+	req.Len(kustomizationDiffs[1].Resources, 2)
+	assert.Equal(t, "v1/ConfigMap/app/config", kustomizationDiffs[1].Resources[0].ID.String())
+	assert.Equal(t, diff.Changed, kustomizationDiffs[1].Resources[0].Change)
+	assert.Contains(t, kustomizationDiffs[1].Resources[0].Diff, "-  version: \"1\"\n+  version: \"2\"\n")
+	assert.Equal(t, "v1/Service/app/web", kustomizationDiffs[1].Resources[1].ID.String())
+	assert.Equal(t, diff.Added, kustomizationDiffs[1].Resources[1].Change)
+}
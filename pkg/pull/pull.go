@@ -7,18 +7,22 @@ import (
 
 	"github.com/pkg/errors"
 	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
-	kotsscheme "github.com/replicatedhq/kots/kotskinds/client/kotsclientset/scheme"
 	"github.com/replicatedhq/kots/pkg/base"
+	"github.com/replicatedhq/kots/pkg/config"
 	"github.com/replicatedhq/kots/pkg/downstream"
+	kotslicense "github.com/replicatedhq/kots/pkg/license"
 	"github.com/replicatedhq/kots/pkg/logger"
 	"github.com/replicatedhq/kots/pkg/midstream"
 	"github.com/replicatedhq/kots/pkg/upstream"
-	"k8s.io/client-go/kubernetes/scheme"
+	"github.com/replicatedhq/kots/pkg/util"
 	"sigs.k8s.io/kustomize/v3/pkg/image"
 )
 
 type PullOptions struct {
 	HelmRepoURI         string
+	HelmRepoOptions     upstream.HelmRepoOptions
+	HelmCacheOptions    upstream.HelmCacheOptions
+	ValidateConfig      bool
 	RootDir             string
 	Namespace           string
 	Downstreams         []string
@@ -32,6 +36,17 @@ type PullOptions struct {
 	RewriteImages       bool
 	RewriteImageOptions RewriteImageOptions
 	HelmOptions         []string
+	HelmValuesFiles     []string
+	KubeVersion         string
+	APIVersions         []string
+	Kubeconfig          string
+	ClusterInfoFile     string
+	Strict              bool
+	RotateRandomStrings bool
+	Force               bool
+
+	// dryRun is set by DryRunPull to pull into a copy of the app
+	dryRun *DryRun
 }
 
 type RewriteImageOptions struct {
@@ -43,6 +58,10 @@ type RewriteImageOptions struct {
 // PullApplicationMetadata will return the application metadata yaml, if one is
 // available for the upstream
 func PullApplicationMetadata(upstreamURI string) ([]byte, error) {
+	if !util.IsURL(upstreamURI) {
+		return nil, nil
+	}
+
 	u, err := url.ParseRequestURI(upstreamURI)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to parse uri")
@@ -64,6 +83,10 @@ func PullApplicationMetadata(upstreamURI string) ([]byte, error) {
 // CanPullUpstream will return a bool indicating if the specified upstream
 // is accessible and authenticed for us.
 func CanPullUpstream(upstreamURI string, pullOptions PullOptions) (bool, error) {
+	if !util.IsURL(upstreamURI) {
+		return true, nil
+	}
+
 	u, err := url.ParseRequestURI(upstreamURI)
 	if err != nil {
 		return false, errors.Wrap(err, "failed to parse uri")
@@ -89,14 +112,23 @@ func Pull(upstreamURI string, pullOptions PullOptions) (string, error) {
 
 	log.Initialize()
 
-	uri, err := url.ParseRequestURI(upstreamURI)
-	if err != nil {
-		return "", errors.Wrap(err, "failed to parse uri")
+	isReplicated := false
+	if util.IsURL(upstreamURI) {
+		uri, err := url.ParseRequestURI(upstreamURI)
+		if err != nil {
+			return "", errors.Wrap(err, "failed to parse uri")
+		}
+
+		isReplicated = uri.Scheme == "replicated"
 	}
 
 	fetchOptions := upstream.FetchOptions{}
 	fetchOptions.HelmRepoURI = pullOptions.HelmRepoURI
+	fetchOptions.HelmRepoOptions = pullOptions.HelmRepoOptions
+	fetchOptions.HelmCacheOptions = pullOptions.HelmCacheOptions
 	fetchOptions.LocalPath = pullOptions.LocalPath
+	fetchOptions.HelmOptions = pullOptions.HelmOptions
+	fetchOptions.HelmValuesFiles = pullOptions.HelmValuesFiles
 
 	if pullOptions.LicenseFile != "" {
 		license, err := parseLicenseFromFile(pullOptions.LicenseFile)
@@ -114,14 +146,42 @@ func Pull(upstreamURI string, pullOptions PullOptions) (string, error) {
 		return "", errors.Wrap(err, "failed to fetch upstream")
 	}
 
-	includeAdminConsole := uri.Scheme == "replicated" && !pullOptions.ExcludeAdminConsole
+	if pullOptions.dryRun != nil {
+		if err := pullOptions.dryRun.prepare(u, &pullOptions); err != nil {
+			log.FinishSpinnerWithError()
+			return "", errors.Wrap(err, "failed to prepare dry run")
+		}
+	}
+
+	includeAdminConsole := isReplicated && !pullOptions.ExcludeAdminConsole
 
 	writeUpstreamOptions := upstream.WriteOptions{
 		RootDir:             pullOptions.RootDir,
 		CreateAppDir:        pullOptions.CreateAppDir,
 		IncludeAdminConsole: includeAdminConsole,
 		SharedPassword:      pullOptions.SharedPassword,
+		RotateRandomStrings: pullOptions.RotateRandomStrings,
+	}
+	writeBaseOptions := base.WriteOptions{
+		BaseDir:          u.GetBaseDir(writeUpstreamOptions),
+		Overwrite:        true,
+		ExcludeKotsKinds: pullOptions.ExcludeKotsKinds,
+		Force:            pullOptions.Force,
 	}
+
+	// local changes to the base are checked before anything is written, so that an app
+	// that can't be updated is left as it was
+	if err := base.CheckLocalChanges(writeBaseOptions); err != nil {
+		log.FinishSpinnerWithError()
+		if conflicts, ok := errors.Cause(err).(base.MergeConflicts); ok {
+			return "", errors.Errorf("%s\nresolve the conflict markers in %s and pull again, or pull again with --force to discard the local changes", conflicts.Error(), writeBaseOptions.BaseDir)
+		}
+		if errors.Cause(err) == base.ErrNoPristineBase {
+			return "", errors.Errorf("%s has no pristine copy to merge local changes with\npull again with --force to replace it, and move any local changes to the overlays", writeBaseOptions.BaseDir)
+		}
+		return "", errors.Wrap(err, "failed to check local changes to base")
+	}
+
 	if err := u.WriteUpstream(writeUpstreamOptions); err != nil {
 		log.FinishSpinnerWithError()
 		return "", errors.Wrap(err, "failed to write upstream")
@@ -164,21 +224,35 @@ func Pull(upstreamURI string, pullOptions PullOptions) (string, error) {
 	renderOptions := base.RenderOptions{
 		SplitMultiDocYAML: true,
 		Namespace:         pullOptions.Namespace,
-		HelmOptions:       pullOptions.HelmOptions,
+		KubeVersion:       pullOptions.KubeVersion,
+		APIVersions:       pullOptions.APIVersions,
+		ValidateConfig:    pullOptions.ValidateConfig,
+		Kubeconfig:        pullOptions.Kubeconfig,
+		ClusterInfoFile:   pullOptions.ClusterInfoFile,
+		Strict:            pullOptions.Strict,
 	}
 	log.ActionWithSpinner("Creating base")
 	b, err := base.RenderUpstream(u, &renderOptions)
 	if err != nil {
+		log.FinishSpinnerWithError()
+		if validationErrors, ok := err.(config.ValidationErrors); ok {
+			configValuesFile := filepath.Join(u.GetUpstreamDir(writeUpstreamOptions), "userdata", "config.yaml")
+			return "", errors.Errorf("%s\nset the values in %s and pull again", validationErrors.Error(), configValuesFile)
+		}
 		return "", errors.Wrap(err, "failed to render upstream")
 	}
 	log.FinishSpinner()
 
-	writeBaseOptions := base.WriteOptions{
-		BaseDir:          u.GetBaseDir(writeUpstreamOptions),
-		Overwrite:        true,
-		ExcludeKotsKinds: pullOptions.ExcludeKotsKinds,
+	// rendering can generate certificates and keys, which are kept in the installation
+	if err := u.WriteInstallation(writeUpstreamOptions); err != nil {
+		return "", errors.Wrap(err, "failed to write installation")
 	}
+
+	// conflicts are marked in the base, and the overlays are written once they're resolved
 	if err := b.WriteBase(writeBaseOptions); err != nil {
+		if conflicts, ok := errors.Cause(err).(base.MergeConflicts); ok {
+			return "", errors.Errorf("%s\nresolve the conflict markers in %s and pull again, or pull again with --force to discard the local changes", conflicts.Error(), writeBaseOptions.BaseDir)
+		}
 		return "", errors.Wrap(err, "failed to write base")
 	}
 
@@ -223,7 +297,7 @@ func Pull(upstreamURI string, pullOptions PullOptions) (string, error) {
 		}
 	}
 
-	return filepath.Join(pullOptions.RootDir, u.Name), nil
+	return appDirFromOptions(u, pullOptions), nil
 }
 
 func parseLicenseFromFile(filename string) (*kotsv1beta1.License, error) {
@@ -232,18 +306,20 @@ func parseLicenseFromFile(filename string) (*kotsv1beta1.License, error) {
 		return nil, errors.Wrap(err, "failed to read license file")
 	}
 
-	kotsscheme.AddToScheme(scheme.Scheme)
-	decode := scheme.Codecs.UniversalDeserializer().Decode
-	license, gvk, err := decode(contents, nil, nil)
+	license, err := kotslicense.ParseLicense(contents)
 	if err != nil {
-		return nil, errors.Wrap(err, "unable to decode license file")
+		return nil, errors.Wrap(err, "failed to parse license")
 	}
 
-	if gvk.Group != "kots.io" || gvk.Version != "v1beta1" || gvk.Kind != "License" {
-		return nil, errors.New("not an application license")
+	return license, nil
+}
+
+func appDirFromOptions(upstream *upstream.Upstream, pullOptions PullOptions) string {
+	if pullOptions.CreateAppDir {
+		return filepath.Join(pullOptions.RootDir, upstream.Name)
 	}
 
-	return license.(*kotsv1beta1.License), nil
+	return pullOptions.RootDir
 }
 
 func imagesDirFromOptions(upstream *upstream.Upstream, pullOptions PullOptions) string {
@@ -0,0 +1,316 @@
+package pull
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/replicatedhq/kots/pkg/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPullKeepsConfigValues(t *testing.T) {
+	req := require.New(t)
+
+	rootDir, err := ioutil.TempDir("", "kots-pull")
+	req.NoError(err)
+	defer os.RemoveAll(rootDir)
+
+	localPath := filepath.Join(rootDir, "release")
+	writeTestApp(t, localPath, map[string]string{
+		"config.yaml": `apiVersion: kots.io/v1beta1
+kind: Config
+metadata:
+  name: app
+spec:
+  groups:
+  - name: database
+    title: Database
+    items:
+    - name: hostname
+      type: text
+      required: true
+    - name: port
+      type: text
+      value: "5432"
+`,
+		"configmap.yaml": `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: database
+data:
+  address: '{{repl ConfigOption "hostname"}}:{{repl ConfigOption "port"}}'
+`,
+	})
+
+	pullOptions := PullOptions{
+		RootDir:             filepath.Join(rootDir, "app"),
+		LocalPath:           localPath,
+		ExcludeAdminConsole: true,
+		ExcludeKotsKinds:    true,
+		ValidateConfig:      true,
+		Silent:              true,
+	}
+
+	_, err = Pull("replicated://app", pullOptions)
+	req.Error(err)
+	configValuesFile := filepath.Join(pullOptions.RootDir, "upstream", "userdata", "config.yaml")
+	assert.Contains(t, err.Error(), "set the values in "+configValuesFile+" and pull again")
+
+	configValues, err := ioutil.ReadFile(configValuesFile)
+	req.NoError(err)
+	req.Contains(string(configValues), "port: \"5432\"")
+	configValues = []byte(strings.Replace(string(configValues), "values:\n", "values:\n    hostname: db.example.com\n", 1))
+	req.NoError(ioutil.WriteFile(configValuesFile, configValues, 0644))
+
+	_, err = Pull("replicated://app", pullOptions)
+	req.NoError(err)
+
+	configMap, err := ioutil.ReadFile(filepath.Join(pullOptions.RootDir, "base", "configmap.yaml"))
+	req.NoError(err)
+	assert.Contains(t, string(configMap), "address: 'db.example.com:5432'")
+
+	// the value is kept on the next pull too, and items that are new get their values
+	writeTestApp(t, localPath, map[string]string{
+		"config.yaml": `apiVersion: kots.io/v1beta1
+kind: Config
+metadata:
+  name: app
+spec:
+  groups:
+  - name: database
+    title: Database
+    items:
+    - name: hostname
+      type: text
+      required: true
+    - name: port
+      type: text
+      value: "5432"
+    - name: database
+      type: text
+      value: app
+`,
+	})
+
+	_, err = Pull("replicated://app", pullOptions)
+	req.NoError(err)
+
+	configValues, err = ioutil.ReadFile(configValuesFile)
+	req.NoError(err)
+	assert.Contains(t, string(configValues), "hostname: db.example.com")
+	assert.Contains(t, string(configValues), "database: app")
+}
+
+func TestPullKeepsConfigRandomStrings(t *testing.T) {
+	req := require.New(t)
+
+	rootDir, err := ioutil.TempDir("", "kots-pull")
+	req.NoError(err)
+	defer os.RemoveAll(rootDir)
+
+	localPath := filepath.Join(rootDir, "release")
+	writeTestApp(t, localPath, map[string]string{
+		"config.yaml": `apiVersion: kots.io/v1beta1
+kind: Config
+metadata:
+  name: app
+spec:
+  groups:
+  - name: database
+    title: Database
+    items:
+    - name: password
+      type: text
+      value: '{{repl RandomString 12}}'
+`,
+		"secret.yaml": `apiVersion: v1
+kind: Secret
+metadata:
+  name: database
+stringData:
+  password: '{{repl ConfigOption "password"}}'
+`,
+	})
+
+	pullOptions := PullOptions{
+		RootDir:             filepath.Join(rootDir, "app"),
+		LocalPath:           localPath,
+		ExcludeAdminConsole: true,
+		ExcludeKotsKinds:    true,
+		Silent:              true,
+	}
+
+	readPassword := func() (string, string) {
+		configValues, err := ioutil.ReadFile(filepath.Join(pullOptions.RootDir, "upstream", "userdata", "config.yaml"))
+		req.NoError(err)
+		secret, err := ioutil.ReadFile(filepath.Join(pullOptions.RootDir, "base", "secret.yaml"))
+		req.NoError(err)
+
+		password := ""
+		for _, line := range strings.Split(string(configValues), "\n") {
+			if strings.HasPrefix(strings.TrimSpace(line), "password: ") {
+				password = strings.TrimPrefix(strings.TrimSpace(line), "password: ")
+			}
+		}
+		req.Len(password, 12)
+
+		return password, string(secret)
+	}
+
+	_, err = Pull("replicated://app", pullOptions)
+	req.NoError(err)
+	firstPassword, firstSecret := readPassword()
+	assert.Contains(t, firstSecret, "password: '"+firstPassword+"'")
+
+	_, err = Pull("replicated://app", pullOptions)
+	req.NoError(err)
+	secondPassword, secondSecret := readPassword()
+	assert.Equal(t, firstPassword, secondPassword)
+	assert.Equal(t, firstSecret, secondSecret)
+
+	// the installation keeps the string for the item encrypted
+	installation, err := ioutil.ReadFile(filepath.Join(pullOptions.RootDir, "upstream", "userdata", "installation.yaml"))
+	req.NoError(err)
+	assert.Contains(t, string(installation), "config/password/value/12/")
+	assert.Contains(t, string(installation), crypto.EncryptedPrefix)
+	assert.NotContains(t, string(installation), firstPassword)
+
+	// rotating generates a new string for the item, which is then kept
+	pullOptions.RotateRandomStrings = true
+	_, err = Pull("replicated://app", pullOptions)
+	req.NoError(err)
+	rotatedPassword, rotatedSecret := readPassword()
+	assert.NotEqual(t, firstPassword, rotatedPassword)
+	assert.Contains(t, rotatedSecret, "password: '"+rotatedPassword+"'")
+
+	pullOptions.RotateRandomStrings = false
+	_, err = Pull("replicated://app", pullOptions)
+	req.NoError(err)
+	keptPassword, _ := readPassword()
+	assert.Equal(t, rotatedPassword, keptPassword)
+
+	// a value that was changed isn't rotated
+	configValuesFile := filepath.Join(pullOptions.RootDir, "upstream", "userdata", "config.yaml")
+	configValues, err := ioutil.ReadFile(configValuesFile)
+	req.NoError(err)
+	configValues = []byte(strings.Replace(string(configValues), "password: "+rotatedPassword, "password: changed12345", 1))
+	req.NoError(ioutil.WriteFile(configValuesFile, configValues, 0644))
+
+	pullOptions.RotateRandomStrings = true
+	_, err = Pull("replicated://app", pullOptions)
+	req.NoError(err)
+	changedPassword, _ := readPassword()
+	assert.Equal(t, "changed12345", changedPassword)
+}
+
+func TestPullBaseWithoutPristine(t *testing.T) {
+	req := require.New(t)
+
+	rootDir, err := ioutil.TempDir("", "kots-pull")
+	req.NoError(err)
+	defer os.RemoveAll(rootDir)
+
+	localPath := filepath.Join(rootDir, "release")
+	writeTestApp(t, localPath, map[string]string{
+		"configmap.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\ndata:\n  version: \"1\"\n",
+	})
+
+	pullOptions := PullOptions{
+		RootDir:             filepath.Join(rootDir, "app"),
+		LocalPath:           localPath,
+		ExcludeAdminConsole: true,
+		ExcludeKotsKinds:    true,
+		Silent:              true,
+	}
+
+	_, err = Pull("replicated://app", pullOptions)
+	req.NoError(err)
+
+	// an app that was pulled before the pristine copy of the base was kept
+	req.NoError(os.RemoveAll(filepath.Join(pullOptions.RootDir, ".kots")))
+	writeTestApp(t, localPath, map[string]string{
+		"configmap.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\ndata:\n  version: \"2\"\n",
+	})
+
+	_, err = Pull("replicated://app", pullOptions)
+	req.Error(err)
+	assert.Contains(t, err.Error(), "pull again with --force")
+
+	// nothing is written
+	upstreamContent, err := ioutil.ReadFile(filepath.Join(pullOptions.RootDir, "upstream", "configmap.yaml"))
+	req.NoError(err)
+	assert.Contains(t, string(upstreamContent), `version: "1"`)
+
+	pullOptions.Force = true
+	_, err = Pull("replicated://app", pullOptions)
+	req.NoError(err)
+
+	baseContent, err := ioutil.ReadFile(filepath.Join(pullOptions.RootDir, "base", "configmap.yaml"))
+	req.NoError(err)
+	assert.Contains(t, string(baseContent), `version: "2"`)
+}
+
+func TestPullMarksBaseConflicts(t *testing.T) {
+	req := require.New(t)
+
+	rootDir, err := ioutil.TempDir("", "kots-pull")
+	req.NoError(err)
+	defer os.RemoveAll(rootDir)
+
+	localPath := filepath.Join(rootDir, "release")
+	writeTestApp(t, localPath, map[string]string{
+		"configmap.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\ndata:\n  version: \"1\"\n",
+	})
+
+	pullOptions := PullOptions{
+		RootDir:             filepath.Join(rootDir, "app"),
+		LocalPath:           localPath,
+		ExcludeAdminConsole: true,
+		ExcludeKotsKinds:    true,
+		Silent:              true,
+	}
+
+	_, err = Pull("replicated://app", pullOptions)
+	req.NoError(err)
+
+	baseFile := filepath.Join(pullOptions.RootDir, "base", "configmap.yaml")
+	localContent := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\ndata:\n  version: local\n"
+	req.NoError(ioutil.WriteFile(baseFile, []byte(localContent), 0644))
+	writeTestApp(t, localPath, map[string]string{
+		"configmap.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\ndata:\n  version: \"2\"\n",
+	})
+
+	// the conflict is marked in the base, and the overlays aren't written
+	pullOptions.Downstreams = []string{"prod"}
+	_, err = Pull("replicated://app", pullOptions)
+	req.Error(err)
+	assert.Contains(t, err.Error(), "resolve the conflict markers in "+filepath.Join(pullOptions.RootDir, "base"))
+
+	baseContent, err := ioutil.ReadFile(baseFile)
+	req.NoError(err)
+	assert.Contains(t, string(baseContent), "<<<<<<< local\n  version: local\n=======\n  version: \"2\"\n>>>>>>> upstream\n")
+
+	downstreamDir := filepath.Join(pullOptions.RootDir, "overlays", "downstreams", "prod")
+	_, err = os.Stat(downstreamDir)
+	assert.True(t, os.IsNotExist(err))
+
+	// pulling again before the conflict is resolved is refused
+	_, err = Pull("replicated://app", pullOptions)
+	req.Error(err)
+	assert.Contains(t, err.Error(), "conflict markers are not resolved")
+
+	// once it's resolved, the resolved base is kept and the overlays are written
+	req.NoError(ioutil.WriteFile(baseFile, []byte(localContent), 0644))
+	_, err = Pull("replicated://app", pullOptions)
+	req.NoError(err)
+
+	baseContent, err = ioutil.ReadFile(baseFile)
+	req.NoError(err)
+	assert.Equal(t, localContent, string(baseContent))
+	_, err = os.Stat(downstreamDir)
+	assert.NoError(t, err)
+}
@@ -16,6 +16,15 @@ var (
 type Builder struct {
 	Ctx    []Ctx
 	Functs template.FuncMap
+	// Strict makes values that can't be converted an error, instead of the default value.
+	// Contexts that are created by the builder, such as the ConfigCtx, are also strict
+	Strict bool
+}
+
+// templateCtx is a context with functions that depend on the name of the template
+// that is rendered
+type templateCtx interface {
+	templateFuncMap(name string) template.FuncMap
 }
 
 func (b *Builder) AddCtx(ctx Ctx) {
@@ -23,10 +32,16 @@ func (b *Builder) AddCtx(ctx Ctx) {
 }
 
 func (b *Builder) String(text string) (string, error) {
+	return b.namedString(text, text)
+}
+
+// namedString renders the text like String, with a name for the template that is
+// more stable than the text
+func (b *Builder) namedString(name string, text string) (string, error) {
 	if text == "" {
 		return "", nil
 	}
-	return b.RenderTemplate(text, text)
+	return b.RenderTemplate(name, text)
 }
 
 func (b *Builder) Bool(text string, defaultVal bool) (bool, error) {
@@ -47,6 +62,9 @@ func (b *Builder) Bool(text string, defaultVal bool) (bool, error) {
 
 	result, err := strconv.ParseBool(value)
 	if err != nil {
+		if b.Strict {
+			return defaultVal, errors.Errorf("%q is not a bool", value)
+		}
 		// for now we are assuming default value if we fail to parse
 		return defaultVal, nil
 	}
@@ -72,6 +90,9 @@ func (b *Builder) Int(text string, defaultVal int64) (int64, error) {
 
 	result, err := strconv.ParseInt(value, 10, 64)
 	if err != nil {
+		if b.Strict {
+			return defaultVal, errors.Errorf("%q is not an int", value)
+		}
 		// for now we are assuming default value if we fail to parse
 		return defaultVal, nil
 	}
@@ -97,6 +118,9 @@ func (b *Builder) Uint(text string, defaultVal uint64) (uint64, error) {
 
 	result, err := strconv.ParseUint(value, 10, 64)
 	if err != nil {
+		if b.Strict {
+			return defaultVal, errors.Errorf("%q is not a uint", value)
+		}
 		// for now we are assuming default value if we fail to parse
 		return defaultVal, nil
 	}
@@ -122,6 +146,9 @@ func (b *Builder) Float64(text string, defaultVal float64) (float64, error) {
 
 	result, err := strconv.ParseFloat(value, 64)
 	if err != nil {
+		if b.Strict {
+			return defaultVal, errors.Errorf("%q is not a float", value)
+		}
 		// for now we are assuming default value if we fail to parse
 		return defaultVal, nil
 	}
@@ -144,7 +171,19 @@ func (b *Builder) BuildFuncMap() template.FuncMap {
 }
 
 func (b *Builder) GetTemplate(name, text string) (*template.Template, error) {
-	tmpl, err := template.New(name).Delims("{{repl ", "}}").Funcs(b.BuildFuncMap()).Parse(text)
+	funcMap := template.FuncMap{}
+	for fnName, fn := range b.BuildFuncMap() {
+		funcMap[fnName] = fn
+	}
+	for _, ctx := range b.Ctx {
+		if ctx, ok := ctx.(templateCtx); ok {
+			for fnName, fn := range ctx.templateFuncMap(name) {
+				funcMap[fnName] = fn
+			}
+		}
+	}
+
+	tmpl, err := template.New(name).Delims("{{repl ", "}}").Funcs(funcMap).Parse(text)
 	if err != nil {
 		return nil, err
 	}
@@ -152,15 +191,17 @@ func (b *Builder) GetTemplate(name, text string) (*template.Template, error) {
 	return tmpl, nil
 }
 
+// RenderTemplate renders the text. Errors are a TemplateError with the location in the
+// text, using the name as the file name
 func (b *Builder) RenderTemplate(name string, text string) (string, error) {
 	tmpl, err := b.GetTemplate(name, text)
 	if err != nil {
-		return "", errors.Wrap(err, "failed to get template")
+		return "", newTemplateError(name, text, err)
 	}
 
 	var contents bytes.Buffer
 	if err := tmpl.Execute(&contents, nil); err != nil {
-		return "", errors.Wrap(err, "failed to execute template")
+		return "", newTemplateError(name, text, err)
 	}
 
 	return contents.String(), nil
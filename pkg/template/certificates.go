@@ -0,0 +1,231 @@
+package template
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
+)
+
+const (
+	defaultKeyBits = 2048
+
+	// defaultCADays is how long a CA is valid for when it is generated for a certificate
+	// before GenerateCA is called for it
+	defaultCADays = 3650
+)
+
+// generator creates the certificates, keys and random strings for templates. They are kept in the
+// generated values by name, and every call with the same name returns the same one
+type generator struct {
+	values *kotsv1beta1.GeneratedValues
+}
+
+func newGenerator(values *kotsv1beta1.GeneratedValues) generator {
+	if values == nil {
+		values = &kotsv1beta1.GeneratedValues{}
+	}
+	if values.Certificates == nil {
+		values.Certificates = map[string]kotsv1beta1.GeneratedCertificate{}
+	}
+	if values.KeyPairs == nil {
+		values.KeyPairs = map[string]kotsv1beta1.GeneratedKeyPair{}
+	}
+	if values.RandomStrings == nil {
+		values.RandomStrings = map[string]string{}
+	}
+
+	return generator{values: values}
+}
+
+// generateCA returns the self signed CA with the common name name
+func (g generator) generateCA(name string, daysValid int) (kotsv1beta1.GeneratedCertificate, error) {
+	if existing, ok := g.values.Certificates[name]; ok {
+		if _, _, err := parseGeneratedCertificate(existing); err == nil {
+			return existing, nil
+		}
+	}
+
+	template := &x509.Certificate{
+		Subject: pkix.Name{
+			CommonName: name,
+		},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+	}
+	generated, err := generateCertificate(template, daysValid, nil, nil)
+	if err != nil {
+		return kotsv1beta1.GeneratedCertificate{}, errors.Wrap(err, "failed to generate ca")
+	}
+
+	g.values.Certificates[name] = generated
+	return generated, nil
+}
+
+// generateCertFromCA returns a certificate for commonName signed by the CA named caName.
+// The alternate names can be DNS names or IP addresses. A certificate that was generated
+// before is only returned if it is still signed by the CA and has the same names
+func (g generator) generateCertFromCA(caName string, commonName string, daysValid int, altNames ...string) (kotsv1beta1.GeneratedCertificate, error) {
+	ca, err := g.generateCA(caName, defaultCADays)
+	if err != nil {
+		return kotsv1beta1.GeneratedCertificate{}, errors.Wrap(err, "failed to get ca")
+	}
+	caCert, caKey, err := parseGeneratedCertificate(ca)
+	if err != nil {
+		return kotsv1beta1.GeneratedCertificate{}, errors.Wrap(err, "failed to parse ca")
+	}
+
+	dnsNames, ipAddresses := splitAltNames(altNames)
+
+	name := caName + "/" + commonName
+	if existing, ok := g.values.Certificates[name]; ok {
+		cert, _, err := parseGeneratedCertificate(existing)
+		if err == nil && cert.CheckSignatureFrom(caCert) == nil && hasNames(cert, dnsNames, ipAddresses) {
+			return existing, nil
+		}
+	}
+
+	template := &x509.Certificate{
+		Subject: pkix.Name{
+			CommonName: commonName,
+		},
+		DNSNames:              dnsNames,
+		IPAddresses:           ipAddresses,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	generated, err := generateCertificate(template, daysValid, caCert, caKey)
+	if err != nil {
+		return kotsv1beta1.GeneratedCertificate{}, errors.Wrap(err, "failed to generate cert")
+	}
+
+	g.values.Certificates[name] = generated
+	return generated, nil
+}
+
+// generateKeyPair returns the RSA key pair named name, with 2048 bits unless the size is given
+func (g generator) generateKeyPair(name string, bits ...int) (kotsv1beta1.GeneratedKeyPair, error) {
+	if existing, ok := g.values.KeyPairs[name]; ok {
+		return existing, nil
+	}
+
+	keyBits := defaultKeyBits
+	if len(bits) > 0 {
+		keyBits = bits[0]
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, keyBits)
+	if err != nil {
+		return kotsv1beta1.GeneratedKeyPair{}, errors.Wrap(err, "failed to generate key")
+	}
+	publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return kotsv1beta1.GeneratedKeyPair{}, errors.Wrap(err, "failed to marshal public key")
+	}
+
+	generated := kotsv1beta1.GeneratedKeyPair{
+		PublicKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey})),
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+	}
+
+	g.values.KeyPairs[name] = generated
+	return generated, nil
+}
+
+// generateCertificate creates a new key and a certificate from template for it, signed by
+// the parent, or self signed if there is no parent
+func generateCertificate(template *x509.Certificate, daysValid int, parent *x509.Certificate, parentKey *rsa.PrivateKey) (kotsv1beta1.GeneratedCertificate, error) {
+	key, err := rsa.GenerateKey(rand.Reader, defaultKeyBits)
+	if err != nil {
+		return kotsv1beta1.GeneratedCertificate{}, errors.Wrap(err, "failed to generate key")
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return kotsv1beta1.GeneratedCertificate{}, errors.Wrap(err, "failed to generate serial number")
+	}
+	template.SerialNumber = serialNumber
+	template.NotBefore = time.Now()
+	template.NotAfter = template.NotBefore.Add(time.Duration(daysValid) * 24 * time.Hour)
+
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		return kotsv1beta1.GeneratedCertificate{}, errors.Wrap(err, "failed to create certificate")
+	}
+
+	return kotsv1beta1.GeneratedCertificate{
+		Cert: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		Key:  string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+	}, nil
+}
+
+func parseGeneratedCertificate(generated kotsv1beta1.GeneratedCertificate) (*x509.Certificate, *rsa.PrivateKey, error) {
+	certBlock, _ := pem.Decode([]byte(generated.Cert))
+	if certBlock == nil {
+		return nil, nil, errors.New("failed to decode cert")
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to parse cert")
+	}
+
+	keyBlock, _ := pem.Decode([]byte(generated.Key))
+	if keyBlock == nil {
+		return nil, nil, errors.New("failed to decode key")
+	}
+	key, err := x509.ParsePKCS1PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to parse key")
+	}
+
+	return cert, key, nil
+}
+
+func splitAltNames(altNames []string) ([]string, []net.IP) {
+	var dnsNames []string
+	var ipAddresses []net.IP
+	for _, altName := range altNames {
+		if ip := net.ParseIP(altName); ip != nil {
+			ipAddresses = append(ipAddresses, ip)
+		} else {
+			dnsNames = append(dnsNames, altName)
+		}
+	}
+
+	return dnsNames, ipAddresses
+}
+
+func hasNames(cert *x509.Certificate, dnsNames []string, ipAddresses []net.IP) bool {
+	certIPAddresses := []string{}
+	for _, ip := range cert.IPAddresses {
+		certIPAddresses = append(certIPAddresses, ip.String())
+	}
+	wantIPAddresses := []string{}
+	for _, ip := range ipAddresses {
+		wantIPAddresses = append(wantIPAddresses, ip.String())
+	}
+	sort.Strings(certIPAddresses)
+	sort.Strings(wantIPAddresses)
+
+	certDNSNames := append([]string{}, cert.DNSNames...)
+	wantDNSNames := append([]string{}, dnsNames...)
+	sort.Strings(certDNSNames)
+	sort.Strings(wantDNSNames)
+
+	return reflect.DeepEqual(certIPAddresses, wantIPAddresses) && reflect.DeepEqual(certDNSNames, wantDNSNames)
+}
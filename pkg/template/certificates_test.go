@@ -0,0 +1,116 @@
+package template
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parseTestCert(t *testing.T, certPEM string) *x509.Certificate {
+	block, _ := pem.Decode([]byte(certPEM))
+	require.NotNil(t, block)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	return cert
+}
+
+func TestGenerateCertFromCA(t *testing.T) {
+	req := require.New(t)
+
+	values := &kotsv1beta1.GeneratedValues{}
+	g := newGenerator(values)
+
+	ca, err := g.generateCA("my-ca", 365)
+	req.NoError(err)
+	caCert := parseTestCert(t, ca.Cert)
+	assert.True(t, caCert.IsCA)
+	assert.Equal(t, "my-ca", caCert.Subject.CommonName)
+
+	cert, err := g.generateCertFromCA("my-ca", "api", 30, "api.default.svc", "10.0.0.1")
+	req.NoError(err)
+	serverCert := parseTestCert(t, cert.Cert)
+	req.NoError(serverCert.CheckSignatureFrom(caCert))
+	assert.Equal(t, "api", serverCert.Subject.CommonName)
+	assert.Equal(t, []string{"api.default.svc"}, serverCert.DNSNames)
+	assert.Equal(t, "10.0.0.1", serverCert.IPAddresses[0].String())
+
+	// the same names return the same certificates
+	sameCA, err := g.generateCA("my-ca", 365)
+	req.NoError(err)
+	assert.Equal(t, ca, sameCA)
+	sameCert, err := g.generateCertFromCA("my-ca", "api", 30, "10.0.0.1", "api.default.svc")
+	req.NoError(err)
+	assert.Equal(t, cert, sameCert)
+
+	// and they're kept in the values
+	assert.Equal(t, ca, values.Certificates["my-ca"])
+	assert.Equal(t, cert, values.Certificates["my-ca/api"])
+
+	// a new alternate name needs a new certificate
+	newCert, err := g.generateCertFromCA("my-ca", "api", 30, "api.default.svc", "api.default.svc.cluster.local")
+	req.NoError(err)
+	assert.NotEqual(t, cert, newCert)
+	assert.Equal(t, []string{"api.default.svc", "api.default.svc.cluster.local"}, parseTestCert(t, newCert.Cert).DNSNames)
+
+	// as does a certificate that isn't signed by the ca anymore
+	delete(values.Certificates, "my-ca")
+	rotatedCert, err := g.generateCertFromCA("my-ca", "api", 30, "api.default.svc", "api.default.svc.cluster.local")
+	req.NoError(err)
+	assert.NotEqual(t, newCert, rotatedCert)
+	req.NoError(parseTestCert(t, rotatedCert.Cert).CheckSignatureFrom(parseTestCert(t, values.Certificates["my-ca"].Cert)))
+}
+
+func TestGenerateKeyPair(t *testing.T) {
+	req := require.New(t)
+
+	g := newGenerator(nil)
+
+	keyPair, err := g.generateKeyPair("signing", 1024)
+	req.NoError(err)
+	assert.Contains(t, keyPair.PublicKey, "BEGIN PUBLIC KEY")
+	assert.Contains(t, keyPair.PrivateKey, "BEGIN RSA PRIVATE KEY")
+
+	sameKeyPair, err := g.generateKeyPair("signing")
+	req.NoError(err)
+	assert.Equal(t, keyPair, sameKeyPair)
+
+	otherKeyPair, err := g.generateKeyPair("encryption", 1024)
+	req.NoError(err)
+	assert.NotEqual(t, keyPair, otherKeyPair)
+}
+
+func TestStaticContextGeneratedValues(t *testing.T) {
+	req := require.New(t)
+
+	values := &kotsv1beta1.GeneratedValues{}
+	text := `{{repl (GenerateCertFromCA "ca" "web" 365 "web.local").Cert | Sha256}}`
+
+	builder := Builder{}
+	builder.AddCtx(StaticCtx{GeneratedValues: values})
+	first, err := builder.RenderTemplate("cert", text)
+	req.NoError(err)
+	req.Len(first, 64)
+
+	// rendering again with the values kept from the first render gives the same certificate
+	builder = Builder{}
+	builder.AddCtx(StaticCtx{GeneratedValues: values.DeepCopy()})
+	second, err := builder.RenderTemplate("cert", text)
+	req.NoError(err)
+	assert.Equal(t, first, second)
+
+	// without them, there's a new certificate
+	builder = Builder{}
+	builder.AddCtx(StaticCtx{GeneratedValues: &kotsv1beta1.GeneratedValues{}})
+	third, err := builder.RenderTemplate("cert", text)
+	req.NoError(err)
+	assert.NotEqual(t, first, third)
+}
+
+func TestStaticContextSha256(t *testing.T) {
+	ctx := StaticCtx{}
+	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", ctx.sha256("hello"))
+}
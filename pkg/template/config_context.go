@@ -3,6 +3,8 @@ package template
 import (
 	"encoding/base64"
 	"fmt"
+	"strconv"
+	"strings"
 	"text/template"
 
 	"github.com/pkg/errors"
@@ -10,40 +12,224 @@ import (
 )
 
 func (b *Builder) NewConfigContext(configGroups []kotsv1beta1.ConfigGroup, templateContext map[string]interface{}) (*ConfigCtx, error) {
+	if templateContext == nil {
+		templateContext = map[string]interface{}{}
+	}
+
 	configCtx := &ConfigCtx{
-		ItemValues: templateContext,
+		ItemValues:   templateContext,
+		Strict:       b.Strict,
+		configGroups: configGroups,
+	}
+
+	// items are rendered after the items that their templates refer to, so that
+	// defaults and values can be derived from other items
+	sortedItems, err := b.sortConfigItems(configGroups)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to sort config items")
+	}
+
+	valueBuilder := Builder{
+		Ctx:    append(append([]Ctx{}, b.Ctx...), configCtx),
+		Strict: b.Strict,
+	}
+	for _, groupItem := range sortedItems {
+		configGroup, configItem := groupItem.group, groupItem.item
+
+		if isMultiValue(configGroup, configItem) {
+			values, err := valueBuilder.buildMultiValue(configGroup, configItem, templateContext)
+			if err != nil {
+				return nil, err
+			}
+			configCtx.ItemValues[configItem.Name] = values
+			continue
+		}
+
+		// if the pending value is different from the built, then use the pending every time
+		builtDefault, err := valueBuilder.renderConfigItemField(configItem, "default", configItem.Default)
+		if err != nil {
+			return nil, err
+		}
+		builtValue, err := valueBuilder.ConfigItemValue(configItem)
+		if err != nil {
+			return nil, err
+		}
+
+		var built string
+		if builtValue != "" {
+			built = builtValue
+		} else {
+			built = builtDefault
+		}
+
+		if v, ok := templateContext[configItem.Name]; ok {
+			built = fmt.Sprintf("%s", v)
+		}
+
+		configCtx.ItemValues[configItem.Name] = built
 	}
 
 	for _, configGroup := range configGroups {
-		for _, configItem := range configGroup.Items {
-			// if the pending value is different from the built, then use the pending every time
-			// We have to ignore errors here because we only have the static context loaded
-			// for rendering. some items have templates that need the config context,
-			// so we can ignore these.
-			builtDefault, _ := b.String(configItem.Default)
-			builtValue, _ := b.String(configItem.Value)
-
-			var built string
-			if builtValue != "" {
-				built = builtValue
-			} else {
-				built = builtDefault
+		if configGroup.Repeatable {
+			if err := valueBuilder.alignRepeatableGroup(configGroup, configCtx.ItemValues); err != nil {
+				return nil, err
 			}
+		}
+	}
 
-			if v, ok := templateContext[configItem.Name]; ok {
-				built = fmt.Sprintf("%s", v)
+	// items that are not enabled have no value, so they are evaluated in order and
+	// conditions can refer to the values of items before them
+	whenBuilder := Builder{
+		Ctx:    append(append([]Ctx{}, b.Ctx...), configCtx),
+		Strict: b.Strict,
+	}
+	for _, configGroup := range configGroups {
+		for _, configItem := range configGroup.Items {
+			enabled, err := whenBuilder.ConfigItemIsEnabled(configItem)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to evaluate when for config item %s", configItem.Name)
 			}
 
-			configCtx.ItemValues[configItem.Name] = built
+			if !enabled {
+				delete(configCtx.ItemValues, configItem.Name)
+			}
 		}
 	}
 
 	return configCtx, nil
 }
 
+// ConfigItemIsEnabled renders the when condition of the config item. Items without a
+// condition are always enabled
+func (b *Builder) ConfigItemIsEnabled(configItem kotsv1beta1.ConfigItem) (bool, error) {
+	return b.Bool(configItem.When, true)
+}
+
+// isMultiValue is true for items that have a list of values instead of a single value
+func isMultiValue(configGroup kotsv1beta1.ConfigGroup, configItem kotsv1beta1.ConfigItem) bool {
+	return configGroup.Repeatable || configItem.Multiple
+}
+
+// buildMultiValue returns the pending values of the item, or its rendered multi_value when
+// there are none. Outside of repeatable groups, an item with a single value or default has
+// a list of one value. In repeatable groups, the default is for each repetition instead
+func (b *Builder) buildMultiValue(configGroup kotsv1beta1.ConfigGroup, configItem kotsv1beta1.ConfigItem, templateContext map[string]interface{}) ([]string, error) {
+	if v, ok := templateContext[configItem.Name]; ok {
+		switch v := v.(type) {
+		case []string:
+			return append([]string{}, v...), nil
+		case []interface{}:
+			values := []string{}
+			for _, value := range v {
+				values = append(values, fmt.Sprintf("%v", value))
+			}
+			return values, nil
+		default:
+			return []string{fmt.Sprintf("%v", v)}, nil
+		}
+	}
+
+	if len(configItem.MultiValue) > 0 {
+		return b.ConfigItemMultiValue(configItem)
+	}
+
+	values := []string{}
+	if configGroup.Repeatable {
+		return values, nil
+	}
+
+	built, err := b.ConfigItemValue(configItem)
+	if err != nil {
+		return nil, err
+	}
+	if built == "" {
+		built, err = b.renderConfigItemField(configItem, "default", configItem.Default)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if built != "" {
+		values = append(values, built)
+	}
+
+	return values, nil
+}
+
+// ConfigItemValue renders the value of the config item. The templates have the same names
+// as in the config context, so the random strings that they generate are the same
+func (b *Builder) ConfigItemValue(configItem kotsv1beta1.ConfigItem) (string, error) {
+	return b.renderConfigItemField(configItem, "value", configItem.Value)
+}
+
+// ConfigItemMultiValue renders the multi_value of the config item, like ConfigItemValue
+func (b *Builder) ConfigItemMultiValue(configItem kotsv1beta1.ConfigItem) ([]string, error) {
+	values := []string{}
+	for i, value := range configItem.MultiValue {
+		built, err := b.renderConfigItemField(configItem, fmt.Sprintf("multiValue/%d", i), value)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, built)
+	}
+
+	return values, nil
+}
+
+// renderConfigItemField renders a template of the config item, and names the item and
+// the field in errors
+func (b *Builder) renderConfigItemField(configItem kotsv1beta1.ConfigItem, field string, text string) (string, error) {
+	built, err := b.namedString(configItemTemplateName(configItem, field), text)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to render %s of config item %s", field, configItem.Name)
+	}
+
+	return built, nil
+}
+
+// configItemTemplateName names the templates of a config item after the item, so that the
+// random strings they generate are kept for the item
+func configItemTemplateName(configItem kotsv1beta1.ConfigItem, field string) string {
+	return fmt.Sprintf("config/%s/%s", configItem.Name, field)
+}
+
+// alignRepeatableGroup gives every item in the group the same number of values, so that
+// the values at an index of each item belong to the same repetition of the group. Items
+// with fewer values are filled in with their default
+func (b *Builder) alignRepeatableGroup(configGroup kotsv1beta1.ConfigGroup, itemValues map[string]interface{}) error {
+	count := 0
+	for _, configItem := range configGroup.Items {
+		if values := itemValues[configItem.Name].([]string); len(values) > count {
+			count = len(values)
+		}
+	}
+
+	for _, configItem := range configGroup.Items {
+		values := itemValues[configItem.Name].([]string)
+		if len(values) == count {
+			continue
+		}
+
+		builtDefault, err := b.renderConfigItemField(configItem, "default", configItem.Default)
+		if err != nil {
+			return err
+		}
+		for len(values) < count {
+			values = append(values, builtDefault)
+		}
+		itemValues[configItem.Name] = values
+	}
+
+	return nil
+}
+
 // ConfigCtx is the context for builder functions before the application has started.
+// Items with multiple values, and items in repeatable groups, have a []string value.
+// In strict mode, referring to an item that is not in the config is an error.
 type ConfigCtx struct {
 	ItemValues map[string]interface{}
+	Strict     bool
+
+	configGroups []kotsv1beta1.ConfigGroup
 }
 
 // FuncMap represents the available functions in the ConfigCtx.
@@ -51,58 +237,145 @@ func (ctx ConfigCtx) FuncMap() template.FuncMap {
 	return template.FuncMap{
 		"ConfigOption":          ctx.configOption,
 		"ConfigOptionIndex":     ctx.configOptionIndex,
+		"ConfigOptionList":      ctx.configOptionList,
 		"ConfigOptionData":      ctx.configOptionData,
 		"ConfigOptionEquals":    ctx.configOptionEquals,
 		"ConfigOptionNotEquals": ctx.configOptionNotEquals,
 	}
 }
 
-func (ctx ConfigCtx) configOption(name string) string {
+func (ctx ConfigCtx) configOption(name string) (string, error) {
+	if err := ctx.checkConfigItem(name); err != nil {
+		return "", err
+	}
+
 	v, err := ctx.getConfigOptionValue(name)
 	if err != nil {
-		return ""
+		return "", nil
 	}
-	return v
+	return v, nil
 }
 
-func (ctx ConfigCtx) configOptionIndex(name string) string {
-	return ""
+// configOptionIndex returns the index of the selected option of a select_one item, or an
+// empty string when the item has no option selected
+func (ctx ConfigCtx) configOptionIndex(name string) (string, error) {
+	if err := ctx.checkConfigItem(name); err != nil {
+		return "", err
+	}
+
+	v, err := ctx.getConfigOptionValue(name)
+	if err != nil {
+		return "", nil
+	}
+
+	configItem := ctx.findConfigItem(name)
+	if configItem == nil {
+		return "", nil
+	}
+	for i, childItem := range configItem.Items {
+		if childItem.Name == v {
+			return strconv.Itoa(i), nil
+		}
+	}
+
+	return "", nil
+}
+
+// configOptionList returns all of the values of the item, so that templates can range
+// over items with multiple values and items in repeatable groups
+func (ctx ConfigCtx) configOptionList(name string) ([]string, error) {
+	if err := ctx.checkConfigItem(name); err != nil {
+		return nil, err
+	}
+
+	val, ok := ctx.ItemValues[name]
+	if !ok {
+		return []string{}, nil
+	}
+
+	if values, ok := val.([]string); ok {
+		return values, nil
+	}
+
+	v := fmt.Sprintf("%s", val)
+	if v == "" {
+		return []string{}, nil
+	}
+	return []string{v}, nil
 }
 
-func (ctx ConfigCtx) configOptionData(name string) string {
+func (ctx ConfigCtx) configOptionData(name string) (string, error) {
+	if err := ctx.checkConfigItem(name); err != nil {
+		return "", err
+	}
+
 	v, err := ctx.getConfigOptionValue(name)
 	if err != nil {
-		return ""
+		return "", nil
 	}
 
 	decoded, err := base64.StdEncoding.DecodeString(v)
 	if err != nil {
-		return ""
+		return "", nil
 	}
 
-	return string(decoded)
+	return string(decoded), nil
 }
 
-func (ctx ConfigCtx) configOptionEquals(name string, value string) bool {
+func (ctx ConfigCtx) configOptionEquals(name string, value string) (bool, error) {
+	if err := ctx.checkConfigItem(name); err != nil {
+		return false, err
+	}
+
 	val, err := ctx.getConfigOptionValue(name)
 	if err != nil {
-		return false
+		return false, nil
 	}
 
-	return value == val
+	return value == val, nil
 }
 
-func (ctx ConfigCtx) configOptionNotEquals(name string, value string) bool {
+func (ctx ConfigCtx) configOptionNotEquals(name string, value string) (bool, error) {
+	if err := ctx.checkConfigItem(name); err != nil {
+		return false, err
+	}
+
 	val, err := ctx.getConfigOptionValue(name)
 	if err != nil {
-		return false
+		return false, nil
 	}
 
-	return value != val
+	return value != val, nil
+}
+
+// checkConfigItem returns an error in strict mode when the item is not in the config.
+// Items that are in the config but are not enabled have no value, and are not an error
+func (ctx ConfigCtx) checkConfigItem(name string) error {
+	if !ctx.Strict || ctx.findConfigItem(name) != nil {
+		return nil
+	}
+
+	return errors.Errorf("config item %s is not defined", name)
+}
+
+func (ctx ConfigCtx) findConfigItem(name string) *kotsv1beta1.ConfigItem {
+	for _, configGroup := range ctx.configGroups {
+		for i := range configGroup.Items {
+			if configGroup.Items[i].Name == name {
+				return &configGroup.Items[i]
+			}
+		}
+	}
+
+	return nil
 }
 
 func (ctx ConfigCtx) getConfigOptionValue(itemName string) (string, error) {
 	if val, ok := ctx.ItemValues[itemName]; ok {
+		// items with multiple values are a comma separated list
+		if values, ok := val.([]string); ok {
+			return strings.Join(values, ","), nil
+		}
 		return fmt.Sprintf("%s", val), nil
 	}
 
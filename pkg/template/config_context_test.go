@@ -0,0 +1,407 @@
+package template
+
+import (
+	"testing"
+
+	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewConfigContextWhen(t *testing.T) {
+	configGroups := []kotsv1beta1.ConfigGroup{
+		{
+			Name: "database",
+			Items: []kotsv1beta1.ConfigItem{
+				{
+					Name:    "postgres_type",
+					Type:    "select_one",
+					Default: "embedded",
+				},
+				{
+					Name:    "embedded_password",
+					Type:    "password",
+					Default: "secret",
+					When:    `{{repl ConfigOptionEquals "postgres_type" "embedded"}}`,
+				},
+				{
+					Name:    "external_host",
+					Type:    "text",
+					Default: "db.example.com",
+					When:    `{{repl ConfigOptionEquals "postgres_type" "external"}}`,
+				},
+				{
+					Name:    "external_port",
+					Type:    "text",
+					Default: "5432",
+					When:    `{{repl ConfigOptionNotEquals "external_host" ""}}`,
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name            string
+		templateContext map[string]interface{}
+		expected        map[string]interface{}
+	}{
+		{
+			name: "defaults",
+			expected: map[string]interface{}{
+				"postgres_type":     "embedded",
+				"embedded_password": "secret",
+			},
+		},
+		{
+			name: "external",
+			templateContext: map[string]interface{}{
+				"postgres_type": "external",
+			},
+			expected: map[string]interface{}{
+				"postgres_type": "external",
+				"external_host": "db.example.com",
+				"external_port": "5432",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := require.New(t)
+
+			builder := Builder{}
+			builder.AddCtx(StaticCtx{})
+
+			configCtx, err := builder.NewConfigContext(configGroups, test.templateContext)
+			req.NoError(err)
+			assert.Equal(t, test.expected, configCtx.ItemValues)
+		})
+	}
+}
+
+func TestNewConfigContextMultiValue(t *testing.T) {
+	configGroups := []kotsv1beta1.ConfigGroup{
+		{
+			Name: "database",
+			Items: []kotsv1beta1.ConfigItem{
+				{
+					Name: "postgres_type",
+					Type: "select_one",
+					Items: []kotsv1beta1.ConfigChildItem{
+						{Name: "embedded"},
+						{Name: "external"},
+					},
+					Default: "external",
+				},
+				{
+					Name:       "cidrs",
+					Type:       "text",
+					Multiple:   true,
+					MultiValue: []string{"10.0.0.0/8", "192.168.0.0/16"},
+				},
+			},
+		},
+		{
+			Name:       "ingress",
+			Repeatable: true,
+			Items: []kotsv1beta1.ConfigItem{
+				{
+					Name: "ingress_host",
+					Type: "text",
+				},
+				{
+					Name:    "ingress_port",
+					Type:    "text",
+					Default: "443",
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name            string
+		templateContext map[string]interface{}
+		template        string
+		expected        string
+	}{
+		{
+			name:     "option index",
+			template: `{{repl ConfigOptionIndex "postgres_type"}}`,
+			expected: "1",
+		},
+		{
+			name: "option index without a matching option",
+			templateContext: map[string]interface{}{
+				"postgres_type": "sqlite",
+			},
+			template: `{{repl ConfigOptionIndex "postgres_type"}}`,
+			expected: "",
+		},
+		{
+			name:     "multi value defaults",
+			template: `{{repl range ConfigOptionList "cidrs"}}{{repl .}};{{repl end}}`,
+			expected: "10.0.0.0/8;192.168.0.0/16;",
+		},
+		{
+			name: "multi value as a single option",
+			templateContext: map[string]interface{}{
+				"cidrs": []string{"172.16.0.0/12"},
+			},
+			template: `{{repl ConfigOption "cidrs"}} {{repl len (ConfigOptionList "cidrs")}}`,
+			expected: "172.16.0.0/12 1",
+		},
+		{
+			name:     "single value as a list",
+			template: `{{repl ConfigOptionList "postgres_type"}}`,
+			expected: "[external]",
+		},
+		{
+			name:     "repeatable group with no values",
+			template: `{{repl len (ConfigOptionList "ingress_host")}}`,
+			expected: "0",
+		},
+		{
+			name: "repeatable group",
+			templateContext: map[string]interface{}{
+				"ingress_host": []interface{}{"a.example.com", "b.example.com"},
+				"ingress_port": []string{"8443"},
+			},
+			template: `{{repl $ports := ConfigOptionList "ingress_port"}}{{repl range $i, $host := ConfigOptionList "ingress_host"}}{{repl $host}}:{{repl index $ports $i}} {{repl end}}`,
+			expected: "a.example.com:8443 b.example.com:443 ",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := require.New(t)
+
+			builder := Builder{}
+			builder.AddCtx(StaticCtx{})
+
+			configCtx, err := builder.NewConfigContext(configGroups, test.templateContext)
+			req.NoError(err)
+			builder.AddCtx(configCtx)
+
+			rendered, err := builder.RenderTemplate(test.name, test.template)
+			req.NoError(err)
+			assert.Equal(t, test.expected, rendered)
+		})
+	}
+}
+
+func TestNewConfigContextDependencies(t *testing.T) {
+	tests := []struct {
+		name            string
+		configItems     []kotsv1beta1.ConfigItem
+		templateContext map[string]interface{}
+		expected        map[string]interface{}
+		expectedErr     string
+	}{
+		{
+			name: "derived default of a later item",
+			configItems: []kotsv1beta1.ConfigItem{
+				{Name: "url", Default: `https://{{repl ConfigOption "address"}}`},
+				{Name: "address", Default: `{{repl ConfigOption "hostname"}}:443`},
+				{Name: "hostname", Default: "example.com"},
+			},
+			expected: map[string]interface{}{
+				"url":      "https://example.com:443",
+				"address":  "example.com:443",
+				"hostname": "example.com",
+			},
+		},
+		{
+			name: "derived from a pending value",
+			configItems: []kotsv1beta1.ConfigItem{
+				{Name: "address", Default: `{{repl ConfigOption "hostname"}}:443`},
+				{Name: "hostname", Default: "example.com"},
+			},
+			templateContext: map[string]interface{}{
+				"hostname": "kots.io",
+			},
+			expected: map[string]interface{}{
+				"address":  "kots.io:443",
+				"hostname": "kots.io",
+			},
+		},
+		{
+			name: "references in conditions and pipelines",
+			configItems: []kotsv1beta1.ConfigItem{
+				{Name: "scheme", Default: `{{repl if ConfigOptionEquals "tls" "1"}}https{{repl else}}http{{repl end}}`},
+				{Name: "upper", Default: `{{repl ConfigOption "hostname" | upper}}`},
+				{Name: "tls", Default: "1"},
+				{Name: "hostname", Default: "example.com"},
+			},
+			expected: map[string]interface{}{
+				"scheme":   "https",
+				"upper":    "EXAMPLE.COM",
+				"tls":      "1",
+				"hostname": "example.com",
+			},
+		},
+		{
+			name: "cycle",
+			configItems: []kotsv1beta1.ConfigItem{
+				{Name: "a", Default: `{{repl ConfigOption "b"}}`},
+				{Name: "b", Default: `{{repl ConfigOption "c"}}`},
+				{Name: "c", Value: `{{repl ConfigOption "a"}}`},
+			},
+			expectedErr: "config items refer to each other in a cycle: a -> b -> c -> a",
+		},
+		{
+			name: "self reference",
+			configItems: []kotsv1beta1.ConfigItem{
+				{Name: "a", Default: `{{repl ConfigOption "a"}}`},
+			},
+			expectedErr: "config items refer to each other in a cycle: a -> a",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := require.New(t)
+
+			builder := Builder{}
+			builder.AddCtx(StaticCtx{})
+
+			configGroups := []kotsv1beta1.ConfigGroup{
+				{
+					Name:  "group",
+					Items: test.configItems,
+				},
+			}
+			configCtx, err := builder.NewConfigContext(configGroups, test.templateContext)
+			if test.expectedErr != "" {
+				req.Error(err)
+				assert.Contains(t, err.Error(), test.expectedErr)
+				return
+			}
+			req.NoError(err)
+			assert.Equal(t, test.expected, configCtx.ItemValues)
+		})
+	}
+}
+
+func TestNewConfigContextErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		configGroups []kotsv1beta1.ConfigGroup
+		expectedErr  string
+	}{
+		{
+			name: "unknown function in a default",
+			configGroups: []kotsv1beta1.ConfigGroup{
+				{
+					Name:  "group",
+					Items: []kotsv1beta1.ConfigItem{{Name: "a", Default: `{{repl NotAFunction}}`}},
+				},
+			},
+			expectedErr: "failed to render default of config item a",
+		},
+		{
+			name: "syntax error in a value",
+			configGroups: []kotsv1beta1.ConfigGroup{
+				{
+					Name:  "group",
+					Items: []kotsv1beta1.ConfigItem{{Name: "a", Value: `{{repl ConfigOption "b"`}, {Name: "b"}},
+				},
+			},
+			expectedErr: "failed to render value of config item a",
+		},
+		{
+			name: "error in a multi value",
+			configGroups: []kotsv1beta1.ConfigGroup{
+				{
+					Name:  "group",
+					Items: []kotsv1beta1.ConfigItem{{Name: "a", Multiple: true, MultiValue: []string{"x", `{{repl NotAFunction}}`}}},
+				},
+			},
+			expectedErr: "failed to render multiValue/1 of config item a",
+		},
+		{
+			name: "error in the default of a repeatable item",
+			configGroups: []kotsv1beta1.ConfigGroup{
+				{
+					Name:       "group",
+					Repeatable: true,
+					Items: []kotsv1beta1.ConfigItem{
+						{Name: "a", MultiValue: []string{"x"}},
+						{Name: "b", Default: `{{repl NotAFunction}}`},
+					},
+				},
+			},
+			expectedErr: "failed to render default of config item b",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			builder := Builder{}
+			builder.AddCtx(StaticCtx{})
+
+			_, err := builder.NewConfigContext(test.configGroups, nil)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.expectedErr)
+		})
+	}
+}
+
+func TestNewConfigContextStrict(t *testing.T) {
+	tests := []struct {
+		name        string
+		configItems []kotsv1beta1.ConfigItem
+		expected    map[string]interface{}
+		expectedErr string
+	}{
+		{
+			name: "undefined item in a default",
+			configItems: []kotsv1beta1.ConfigItem{
+				{Name: "a", Default: `{{repl ConfigOption "missing"}}`},
+			},
+			expected:    map[string]interface{}{"a": ""},
+			expectedErr: "failed to render default of config item a",
+		},
+		{
+			name: "undefined item in a value",
+			configItems: []kotsv1beta1.ConfigItem{
+				{Name: "a", Value: `{{repl ConfigOptionEquals "missing" "1"}}`},
+			},
+			expected:    map[string]interface{}{"a": "false"},
+			expectedErr: "failed to render value of config item a",
+		},
+		{
+			name: "undefined item in a multi value",
+			configItems: []kotsv1beta1.ConfigItem{
+				{Name: "a", Multiple: true, MultiValue: []string{`{{repl ConfigOption "missing"}}`}},
+			},
+			expected:    map[string]interface{}{"a": []string{""}},
+			expectedErr: "failed to render multiValue/0 of config item a",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := require.New(t)
+
+			configGroups := []kotsv1beta1.ConfigGroup{
+				{
+					Name:  "group",
+					Items: test.configItems,
+				},
+			}
+
+			builder := Builder{}
+			builder.AddCtx(StaticCtx{})
+			configCtx, err := builder.NewConfigContext(configGroups, nil)
+			req.NoError(err)
+			assert.Equal(t, test.expected, configCtx.ItemValues)
+
+			strictBuilder := Builder{Strict: true}
+			strictBuilder.AddCtx(StaticCtx{})
+			_, err = strictBuilder.NewConfigContext(configGroups, nil)
+			req.Error(err)
+			assert.Contains(t, err.Error(), test.expectedErr)
+			assert.Contains(t, err.Error(), "config item missing is not defined")
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package template
+
+import (
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/pkg/errors"
+	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
+)
+
+type configGroupItem struct {
+	group kotsv1beta1.ConfigGroup
+	item  kotsv1beta1.ConfigItem
+}
+
+// sortConfigItems returns the config items ordered so that every item comes after the
+// items that its templates refer to. Items keep the order of the config otherwise. A
+// reference cycle is an error
+func (b *Builder) sortConfigItems(configGroups []kotsv1beta1.ConfigGroup) ([]configGroupItem, error) {
+	items := []configGroupItem{}
+	itemsByName := map[string]configGroupItem{}
+	for _, configGroup := range configGroups {
+		for _, configItem := range configGroup.Items {
+			items = append(items, configGroupItem{group: configGroup, item: configItem})
+			itemsByName[configItem.Name] = items[len(items)-1]
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[string]int{}
+	sorted := []configGroupItem{}
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			cycle := append(path[indexOf(path, name):], name)
+			return errors.Errorf("config items refer to each other in a cycle: %s", strings.Join(cycle, " -> "))
+		}
+
+		state[name] = visiting
+		groupItem := itemsByName[name]
+		for _, dependency := range b.configItemDependencies(groupItem.item) {
+			if _, ok := itemsByName[dependency]; !ok {
+				continue
+			}
+			if err := visit(dependency, append(append([]string{}, path...), name)); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		sorted = append(sorted, groupItem)
+
+		return nil
+	}
+
+	for _, groupItem := range items {
+		if err := visit(groupItem.item.Name, []string{}); err != nil {
+			return nil, err
+		}
+	}
+
+	return sorted, nil
+}
+
+// configItemDependencies returns the names of the items that the default, value and multi
+// value templates of the item refer to. Only references with a literal item name, such as
+// ConfigOption "hostname", can be found
+func (b *Builder) configItemDependencies(configItem kotsv1beta1.ConfigItem) []string {
+	funcMap := b.parseFuncMap()
+	texts := append([]string{configItem.Default, configItem.Value}, configItem.MultiValue...)
+
+	dependencies := []string{}
+	for _, text := range texts {
+		if !strings.Contains(text, "ConfigOption") {
+			continue
+		}
+
+		// the functions only need to be defined to parse the template, they are not called.
+		// templates that don't parse will fail to render, and have no dependencies
+		tmpl, err := template.New(configItem.Name).Delims("{{repl ", "}}").Funcs(funcMap).Parse(text)
+		if err != nil {
+			continue
+		}
+		for _, reference := range configOptionReferences(tmpl.Tree.Root) {
+			dependencies = append(dependencies, reference.name)
+		}
+	}
+
+	return dependencies
+}
+
+// parseFuncMap returns the functions of the builder and the config context
+func (b *Builder) parseFuncMap() template.FuncMap {
+	funcMap := template.FuncMap{}
+	for name, fn := range b.BuildFuncMap() {
+		funcMap[name] = fn
+	}
+	for name, fn := range (ConfigCtx{}).FuncMap() {
+		funcMap[name] = fn
+	}
+	return funcMap
+}
+
+// configOptionReference is a config item name in a call to one of the ConfigOption functions
+type configOptionReference struct {
+	name string
+	node *parse.CommandNode
+}
+
+func configOptionReferences(node parse.Node) []configOptionReference {
+	references := []configOptionReference{}
+
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return references
+		}
+		for _, n := range node.Nodes {
+			references = append(references, configOptionReferences(n)...)
+		}
+	case *parse.ActionNode:
+		references = append(references, configOptionReferences(node.Pipe)...)
+	case *parse.PipeNode:
+		if node == nil {
+			return references
+		}
+		for _, cmd := range node.Cmds {
+			references = append(references, configOptionReferences(cmd)...)
+		}
+	case *parse.CommandNode:
+		if len(node.Args) > 1 {
+			identifier, isIdentifier := node.Args[0].(*parse.IdentifierNode)
+			name, isString := node.Args[1].(*parse.StringNode)
+			if isIdentifier && isString && strings.HasPrefix(identifier.Ident, "ConfigOption") {
+				references = append(references, configOptionReference{name: name.Text, node: node})
+			}
+		}
+		for _, arg := range node.Args {
+			references = append(references, configOptionReferences(arg)...)
+		}
+	case *parse.IfNode:
+		references = append(references, configOptionBranchReferences(&node.BranchNode)...)
+	case *parse.RangeNode:
+		references = append(references, configOptionBranchReferences(&node.BranchNode)...)
+	case *parse.WithNode:
+		references = append(references, configOptionBranchReferences(&node.BranchNode)...)
+	case *parse.TemplateNode:
+		references = append(references, configOptionReferences(node.Pipe)...)
+	}
+
+	return references
+}
+
+func configOptionBranchReferences(node *parse.BranchNode) []configOptionReference {
+	references := configOptionReferences(node.Pipe)
+	references = append(references, configOptionReferences(node.List)...)
+	references = append(references, configOptionReferences(node.ElseList)...)
+	return references
+}
+
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,83 @@
+package template
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	templateErrorLocationRegexp = regexp.MustCompile(`(?s)^(\d+)(?::(\d+))?: (.*)$`)
+)
+
+// TemplateError is an error rendering a template, with where in the template it happened.
+// Line and Column start at 1, and are 0 when they are not known
+type TemplateError struct {
+	Name       string
+	Line       int
+	Column     int
+	Expression string
+	Message    string
+}
+
+func (e TemplateError) Error() string {
+	location := e.Name
+	if e.Line > 0 {
+		location = fmt.Sprintf("%s:%d", location, e.Line)
+		if e.Column > 0 {
+			location = fmt.Sprintf("%s:%d", location, e.Column)
+		}
+	}
+
+	if e.Expression != "" {
+		return fmt.Sprintf("%s: %s: %s", location, e.Message, e.Expression)
+	}
+	return fmt.Sprintf("%s: %s", location, e.Message)
+}
+
+// newTemplateError finds the location in errors from text/template, which look like
+// `template: name:line:col: executing "name" at <expression>: message` when executing, and
+// `template: name:line: message` when parsing. Parse errors have no expression, so the
+// source line is used instead
+func newTemplateError(name string, text string, err error) TemplateError {
+	templateError := TemplateError{
+		Name:    name,
+		Message: err.Error(),
+	}
+
+	prefix := fmt.Sprintf("template: %s:", name)
+	if !strings.HasPrefix(templateError.Message, prefix) {
+		return templateError
+	}
+
+	matches := templateErrorLocationRegexp.FindStringSubmatch(strings.TrimPrefix(templateError.Message, prefix))
+	if matches == nil {
+		return templateError
+	}
+
+	templateError.Line, _ = strconv.Atoi(matches[1])
+	if matches[2] != "" {
+		// text/template columns start at 0
+		column, _ := strconv.Atoi(matches[2])
+		templateError.Column = column + 1
+	}
+	templateError.Message = matches[3]
+
+	executingPrefix := fmt.Sprintf("executing %q at <", name)
+	if strings.HasPrefix(templateError.Message, executingPrefix) {
+		rest := strings.TrimPrefix(templateError.Message, executingPrefix)
+		if i := strings.Index(rest, ">: "); i != -1 {
+			templateError.Expression = rest[:i]
+			templateError.Message = rest[i+len(">: "):]
+			return templateError
+		}
+	}
+
+	lines := strings.Split(text, "\n")
+	if templateError.Line > 0 && templateError.Line <= len(lines) {
+		templateError.Expression = strings.TrimSpace(lines[templateError.Line-1])
+	}
+
+	return templateError
+}
@@ -0,0 +1,164 @@
+package template
+
+import (
+	"testing"
+
+	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderTemplateErrors(t *testing.T) {
+	configGroups := []kotsv1beta1.ConfigGroup{
+		{
+			Name: "database",
+			Items: []kotsv1beta1.ConfigItem{
+				{Name: "hostname", Default: "db.example.com"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		strict   bool
+		template string
+		expected TemplateError
+	}{
+		{
+			name: "execute",
+			template: `apiVersion: v1
+kind: Service
+spec:
+  externalName: {{repl index (ConfigOptionList "hostname") 3}}
+`,
+			expected: TemplateError{
+				Name:       "service.yaml",
+				Line:       4,
+				Column:     24,
+				Expression: `index (ConfigOptionList "hostname") 3`,
+				Message:    "error calling index: index out of range: 3",
+			},
+		},
+		{
+			name: "unknown function",
+			template: `apiVersion: v1
+kind: Service
+spec:
+  externalName: {{repl ConfigOptoin "hostname"}}
+`,
+			expected: TemplateError{
+				Name:       "service.yaml",
+				Line:       4,
+				Expression: `externalName: {{repl ConfigOptoin "hostname"}}`,
+				Message:    `function "ConfigOptoin" not defined`,
+			},
+		},
+		{
+			name:   "strict undefined config item",
+			strict: true,
+			template: `apiVersion: v1
+kind: Service
+spec:
+  externalName: {{repl ConfigOption "host_name"}}
+`,
+			expected: TemplateError{
+				Name:       "service.yaml",
+				Line:       4,
+				Column:     24,
+				Expression: `ConfigOption "host_name"`,
+				Message:    "error calling ConfigOption: config item host_name is not defined",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := require.New(t)
+
+			builder := Builder{Strict: test.strict}
+			builder.AddCtx(StaticCtx{})
+			configCtx, err := builder.NewConfigContext(configGroups, nil)
+			req.NoError(err)
+			builder.AddCtx(configCtx)
+
+			_, err = builder.RenderTemplate("service.yaml", test.template)
+			req.Error(err)
+			assert.Equal(t, test.expected, err)
+		})
+	}
+}
+
+func TestTemplateErrorError(t *testing.T) {
+	assert.Equal(t, `service.yaml:4:24: error calling ConfigOption: config item host_name is not defined: ConfigOption "host_name"`, TemplateError{
+		Name:       "service.yaml",
+		Line:       4,
+		Column:     24,
+		Expression: `ConfigOption "host_name"`,
+		Message:    "error calling ConfigOption: config item host_name is not defined",
+	}.Error())
+
+	assert.Equal(t, "service.yaml: failed", TemplateError{Name: "service.yaml", Message: "failed"}.Error())
+}
+
+func TestStrict(t *testing.T) {
+	req := require.New(t)
+
+	configGroups := []kotsv1beta1.ConfigGroup{
+		{
+			Name: "database",
+			Items: []kotsv1beta1.ConfigItem{
+				{Name: "hostname", Default: "db.example.com"},
+				{Name: "port", Default: "5432", When: "false"},
+			},
+		},
+	}
+
+	builder := Builder{}
+	builder.AddCtx(StaticCtx{})
+	configCtx, err := builder.NewConfigContext(configGroups, nil)
+	req.NoError(err)
+	builder.AddCtx(configCtx)
+
+	// undefined items and values that can't be converted are the default
+	rendered, err := builder.RenderTemplate("test", `{{repl ConfigOption "missing"}}`)
+	req.NoError(err)
+	assert.Equal(t, "", rendered)
+	boolValue, err := builder.Bool("maybe", true)
+	req.NoError(err)
+	assert.True(t, boolValue)
+	intValue, err := builder.Int("five", 5)
+	req.NoError(err)
+	assert.Equal(t, int64(5), intValue)
+
+	strictBuilder := Builder{Strict: true}
+	strictBuilder.AddCtx(StaticCtx{})
+	strictConfigCtx, err := strictBuilder.NewConfigContext(configGroups, nil)
+	req.NoError(err)
+	strictBuilder.AddCtx(strictConfigCtx)
+
+	for _, text := range []string{
+		`{{repl ConfigOption "missing"}}`,
+		`{{repl ConfigOptionData "missing"}}`,
+		`{{repl ConfigOptionEquals "missing" "x"}}`,
+		`{{repl ConfigOptionNotEquals "missing" "x"}}`,
+		`{{repl ConfigOptionIndex "missing"}}`,
+		`{{repl ConfigOptionList "missing"}}`,
+	} {
+		_, err = strictBuilder.RenderTemplate("test", text)
+		assert.Error(t, err, text)
+	}
+
+	// items that are not enabled are defined
+	rendered, err = strictBuilder.RenderTemplate("test", `{{repl ConfigOption "port"}}`)
+	req.NoError(err)
+	assert.Equal(t, "", rendered)
+
+	_, err = strictBuilder.Bool("maybe", true)
+	assert.Error(t, err)
+	_, err = strictBuilder.Int("five", 5)
+	assert.Error(t, err)
+	_, err = strictBuilder.Uint("-1", 5)
+	assert.Error(t, err)
+	_, err = strictBuilder.Float64("pi", 3.14)
+	assert.Error(t, err)
+}
@@ -0,0 +1,140 @@
+package template
+
+import (
+	"text/template"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/k8sutil"
+	kuberneteserrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// KubernetesCtx is the context for builder functions that read the cluster that the
+// application is deployed to
+type KubernetesCtx struct {
+	Namespace   string
+	ClusterInfo *k8sutil.ClusterInfo
+	// Clientset is used to look up secrets, and is nil when rendering offline
+	Clientset kubernetes.Interface
+}
+
+// NewKubernetesCtx reads the cluster info from the cluster info file when there is one,
+// or from the cluster in the kubeconfig. Without either, only the namespace and the
+// default cluster info are known
+func NewKubernetesCtx(namespace string, kubeconfig string, clusterInfoFile string, defaultClusterInfo *k8sutil.ClusterInfo) (*KubernetesCtx, error) {
+	ctx := &KubernetesCtx{
+		Namespace:   namespace,
+		ClusterInfo: defaultClusterInfo,
+	}
+
+	if clusterInfoFile != "" {
+		clusterInfo, err := k8sutil.ReadClusterInfoFile(clusterInfoFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read cluster info file")
+		}
+		ctx.ClusterInfo = clusterInfo
+		return ctx, nil
+	}
+
+	if kubeconfig == "" {
+		return ctx, nil
+	}
+
+	cfg, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build config")
+	}
+
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create kubernetes clientset")
+	}
+
+	clusterInfo, err := k8sutil.GetClusterInfo(clientset)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster info")
+	}
+	ctx.ClusterInfo = clusterInfo
+	ctx.Clientset = clientset
+
+	return ctx, nil
+}
+
+// FuncMap represents the available functions in the KubernetesCtx.
+func (ctx KubernetesCtx) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"Namespace":      ctx.namespace,
+		"KubeVersion":    ctx.kubeVersion,
+		"HasAPIVersion":  ctx.hasAPIVersion,
+		"NodeCount":      ctx.nodeCount,
+		"StorageClasses": ctx.storageClasses,
+		"LookupSecret":   ctx.lookupSecret,
+	}
+}
+
+func (ctx KubernetesCtx) namespace() string {
+	return ctx.Namespace
+}
+
+func (ctx KubernetesCtx) kubeVersion() string {
+	if ctx.ClusterInfo == nil {
+		return ""
+	}
+	return ctx.ClusterInfo.KubeVersion
+}
+
+// hasAPIVersion returns true when the cluster serves the group version, such as apps/v1
+func (ctx KubernetesCtx) hasAPIVersion(apiVersion string) bool {
+	if ctx.ClusterInfo == nil {
+		return false
+	}
+
+	for _, v := range ctx.ClusterInfo.APIVersions {
+		if v == apiVersion {
+			return true
+		}
+	}
+	return false
+}
+
+func (ctx KubernetesCtx) nodeCount() int {
+	if ctx.ClusterInfo == nil {
+		return 0
+	}
+	return ctx.ClusterInfo.NodeCount
+}
+
+func (ctx KubernetesCtx) storageClasses() []string {
+	if ctx.ClusterInfo == nil {
+		return []string{}
+	}
+	return ctx.ClusterInfo.StorageClasses
+}
+
+// lookupSecret returns the value of the key in the secret in the namespace, or an empty
+// string when there is no such secret or key
+func (ctx KubernetesCtx) lookupSecret(name string, key string) (string, error) {
+	if ctx.Clientset != nil {
+		secret, err := ctx.Clientset.CoreV1().Secrets(ctx.Namespace).Get(name, metav1.GetOptions{})
+		if kuberneteserrors.IsNotFound(err) {
+			return "", nil
+		}
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to get secret %s", name)
+		}
+		return string(secret.Data[key]), nil
+	}
+
+	if ctx.ClusterInfo == nil {
+		return "", nil
+	}
+
+	for _, secret := range ctx.ClusterInfo.Secrets {
+		if secret.Namespace == ctx.Namespace && secret.Name == name {
+			return secret.Data[key], nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,135 @@
+package template
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/replicatedhq/kots/pkg/k8sutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestKubernetesCtx(t *testing.T) {
+	clusterInfo := &k8sutil.ClusterInfo{
+		KubeVersion:    "v1.16.2",
+		APIVersions:    []string{"v1", "apps/v1", "networking.k8s.io/v1beta1"},
+		NodeCount:      3,
+		StorageClasses: []string{"fast", "standard"},
+		Secrets: []k8sutil.ClusterInfoSecret{
+			{Namespace: "app", Name: "db", Data: map[string]string{"password": "offline"}},
+		},
+	}
+
+	clientset := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "app", Name: "db"},
+		Data:       map[string][]byte{"password": []byte("live")},
+	})
+
+	tests := []struct {
+		name     string
+		ctx      KubernetesCtx
+		template string
+		expected string
+	}{
+		{
+			name:     "namespace",
+			ctx:      KubernetesCtx{Namespace: "app"},
+			template: `{{repl Namespace}}`,
+			expected: "app",
+		},
+		{
+			name:     "cluster info",
+			ctx:      KubernetesCtx{Namespace: "app", ClusterInfo: clusterInfo},
+			template: `{{repl KubeVersion}} {{repl NodeCount}} {{repl range StorageClasses}}{{repl .}};{{repl end}}`,
+			expected: "v1.16.2 3 fast;standard;",
+		},
+		{
+			name:     "api versions",
+			ctx:      KubernetesCtx{Namespace: "app", ClusterInfo: clusterInfo},
+			template: `{{repl HasAPIVersion "networking.k8s.io/v1beta1"}} {{repl HasAPIVersion "extensions/v1beta1"}}`,
+			expected: "true false",
+		},
+		{
+			name:     "offline secret",
+			ctx:      KubernetesCtx{Namespace: "app", ClusterInfo: clusterInfo},
+			template: `{{repl LookupSecret "db" "password"}}|{{repl LookupSecret "missing" "password"}}`,
+			expected: "offline|",
+		},
+		{
+			name:     "live secret",
+			ctx:      KubernetesCtx{Namespace: "app", ClusterInfo: clusterInfo, Clientset: clientset},
+			template: `{{repl LookupSecret "db" "password"}}|{{repl LookupSecret "missing" "password"}}`,
+			expected: "live|",
+		},
+		{
+			name:     "secret in another namespace",
+			ctx:      KubernetesCtx{Namespace: "other", Clientset: clientset},
+			template: `{{repl LookupSecret "db" "password"}}`,
+			expected: "",
+		},
+		{
+			name:     "no cluster info",
+			ctx:      KubernetesCtx{},
+			template: `{{repl KubeVersion}}|{{repl NodeCount}}|{{repl HasAPIVersion "v1"}}|{{repl len StorageClasses}}`,
+			expected: "|0|false|0",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			builder := Builder{}
+			builder.AddCtx(StaticCtx{})
+			builder.AddCtx(test.ctx)
+
+			rendered, err := builder.RenderTemplate(test.name, test.template)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, rendered)
+		})
+	}
+}
+
+func TestNewKubernetesCtxClusterInfoFile(t *testing.T) {
+	req := require.New(t)
+
+	dir, err := ioutil.TempDir("", "kots")
+	req.NoError(err)
+	defer os.RemoveAll(dir)
+
+	clusterInfoFile := filepath.Join(dir, "cluster-info.yaml")
+	req.NoError(ioutil.WriteFile(clusterInfoFile, []byte(`kubeVersion: v1.15.3
+apiVersions:
+- v1
+- apps/v1
+nodeCount: 5
+storageClasses:
+- gp2
+secrets:
+- namespace: app
+  name: db
+  data:
+    password: hunter2
+`), 0644))
+
+	ctx, err := NewKubernetesCtx("app", "/does/not/exist", clusterInfoFile, nil)
+	req.NoError(err)
+	assert.Nil(t, ctx.Clientset)
+	assert.Equal(t, &k8sutil.ClusterInfo{
+		KubeVersion:    "v1.15.3",
+		APIVersions:    []string{"v1", "apps/v1"},
+		NodeCount:      5,
+		StorageClasses: []string{"gp2"},
+		Secrets: []k8sutil.ClusterInfoSecret{
+			{Namespace: "app", Name: "db", Data: map[string]string{"password": "hunter2"}},
+		},
+	}, ctx.ClusterInfo)
+
+	defaultClusterInfo := &k8sutil.ClusterInfo{KubeVersion: "1.16.0"}
+	ctx, err = NewKubernetesCtx("app", "", "", defaultClusterInfo)
+	req.NoError(err)
+	assert.Equal(t, defaultClusterInfo, ctx.ClusterInfo)
+}
@@ -0,0 +1,52 @@
+package template
+
+import (
+	"text/template"
+
+	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
+)
+
+// LicenseCtx is the context for builder functions that read the license of the application
+type LicenseCtx struct {
+	License *kotsv1beta1.License
+}
+
+// FuncMap represents the available functions in the LicenseCtx.
+func (ctx LicenseCtx) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"LicenseFieldValue": ctx.licenseFieldValue,
+		"LicenseAppSlug":    ctx.licenseAppSlug,
+		"LicenseIsAirgap":   ctx.licenseIsAirgap,
+	}
+}
+
+// licenseFieldValue returns the value of the entitlement as a string, int or bool, or an
+// empty string when the license has no such entitlement
+func (ctx LicenseCtx) licenseFieldValue(name string) interface{} {
+	if ctx.License == nil {
+		return ""
+	}
+
+	entitlement, ok := ctx.License.Spec.Entitlements[name]
+	if !ok {
+		return ""
+	}
+
+	return entitlement.Value.Value()
+}
+
+func (ctx LicenseCtx) licenseAppSlug() string {
+	if ctx.License == nil {
+		return ""
+	}
+
+	return ctx.License.Spec.AppSlug
+}
+
+func (ctx LicenseCtx) licenseIsAirgap() bool {
+	if ctx.License == nil {
+		return false
+	}
+
+	return ctx.License.Spec.IsAirgapSupported
+}
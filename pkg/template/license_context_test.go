@@ -0,0 +1,90 @@
+package template
+
+import (
+	"testing"
+
+	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLicenseCtx(t *testing.T) {
+	license := &kotsv1beta1.License{
+		Spec: kotsv1beta1.LicenseSpec{
+			AppSlug:           "my-app",
+			IsAirgapSupported: true,
+			Entitlements: map[string]kotsv1beta1.EntitlementField{
+				"seats": {
+					Value: kotsv1beta1.EntitlementValue{Type: kotsv1beta1.EntitlementValueTypeInt, IntVal: 25},
+				},
+				"tier": {
+					Value: kotsv1beta1.EntitlementValue{Type: kotsv1beta1.EntitlementValueTypeString, StrVal: "gold"},
+				},
+				"sso_enabled": {
+					Value: kotsv1beta1.EntitlementValue{Type: kotsv1beta1.EntitlementValueTypeBool, BoolVal: true},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		license  *kotsv1beta1.License
+		template string
+		expected string
+	}{
+		{
+			name:     "int field",
+			license:  license,
+			template: `replicas: {{repl LicenseFieldValue "seats"}}`,
+			expected: "replicas: 25",
+		},
+		{
+			name:     "compare int field",
+			license:  license,
+			template: `{{repl if gt (LicenseFieldValue "seats") 10}}large{{repl else}}small{{repl end}}`,
+			expected: "large",
+		},
+		{
+			name:     "string field",
+			license:  license,
+			template: `{{repl LicenseFieldValue "tier"}}`,
+			expected: "gold",
+		},
+		{
+			name:     "bool field",
+			license:  license,
+			template: `{{repl if LicenseFieldValue "sso_enabled"}}sso{{repl end}}`,
+			expected: "sso",
+		},
+		{
+			name:     "missing field",
+			license:  license,
+			template: `{{repl LicenseFieldValue "missing"}}`,
+			expected: "",
+		},
+		{
+			name:     "app slug and airgap",
+			license:  license,
+			template: `{{repl LicenseAppSlug}} {{repl LicenseIsAirgap}}`,
+			expected: "my-app true",
+		},
+		{
+			name:     "no license",
+			template: `{{repl LicenseAppSlug}} {{repl LicenseIsAirgap}} {{repl LicenseFieldValue "seats"}}`,
+			expected: " false ",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			builder := Builder{}
+			builder.AddCtx(StaticCtx{})
+			builder.AddCtx(LicenseCtx{License: test.license})
+
+			rendered, err := builder.RenderTemplate(test.name, test.template)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, rendered)
+		})
+	}
+}
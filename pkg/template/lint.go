@@ -0,0 +1,52 @@
+package template
+
+import (
+	"fmt"
+	"text/template"
+
+	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
+)
+
+// Lint finds the problems in the template text without rendering it. Syntax errors and
+// calls to functions that are not defined stop the template from parsing, so only the
+// first one is found. When there are config groups, calls to the ConfigOption functions
+// with items that are not in the config are also found
+func Lint(name string, text string, configGroups []kotsv1beta1.ConfigGroup) []TemplateError {
+	tmpl, err := template.New(name).Delims("{{repl ", "}}").Funcs(lintFuncMap()).Parse(text)
+	if err != nil {
+		return []TemplateError{newTemplateError(name, text, err)}
+	}
+
+	lintErrors := []TemplateError{}
+	if configGroups == nil {
+		return lintErrors
+	}
+
+	configCtx := ConfigCtx{configGroups: configGroups}
+	for _, reference := range configOptionReferences(tmpl.Tree.Root) {
+		if configCtx.findConfigItem(reference.name) != nil {
+			continue
+		}
+
+		// format the location like a text/template error to find the line and column
+		location, _ := tmpl.Tree.ErrorContext(reference.node)
+		message := fmt.Sprintf("config item %s is not defined", reference.name)
+		lintError := newTemplateError(name, text, fmt.Errorf("template: %s: %s", location, message))
+		lintError.Expression = reference.node.String()
+
+		lintErrors = append(lintErrors, lintError)
+	}
+
+	return lintErrors
+}
+
+// lintFuncMap returns the functions of all of the contexts that templates can use
+func lintFuncMap() template.FuncMap {
+	funcMap := template.FuncMap{}
+	for _, ctx := range []Ctx{StaticCtx{}, ConfigCtx{}, LicenseCtx{}, KubernetesCtx{}} {
+		for name, fn := range ctx.FuncMap() {
+			funcMap[name] = fn
+		}
+	}
+	return funcMap
+}
@@ -0,0 +1,118 @@
+package template
+
+import (
+	"testing"
+
+	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLint(t *testing.T) {
+	configGroups := []kotsv1beta1.ConfigGroup{
+		{
+			Name: "database",
+			Items: []kotsv1beta1.ConfigItem{
+				{Name: "hostname"},
+				{Name: "port"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		template     string
+		configGroups []kotsv1beta1.ConfigGroup
+		expected     []TemplateError
+	}{
+		{
+			name: "valid",
+			template: `apiVersion: v1
+kind: Service
+metadata:
+  namespace: {{repl Namespace}}
+  labels:
+    tier: {{repl LicenseFieldValue "tier"}}
+spec:
+  externalName: {{repl ConfigOption "hostname"}}:{{repl ConfigOption "port"}}
+`,
+			configGroups: configGroups,
+			expected:     []TemplateError{},
+		},
+		{
+			name: "syntax error",
+			template: `apiVersion: v1
+kind: Service
+spec:
+  externalName: {{repl ConfigOption "hostname"
+`,
+			configGroups: configGroups,
+			expected: []TemplateError{
+				{
+					Name:    "service.yaml",
+					Line:    5,
+					Message: "unclosed action started at service.yaml:4",
+				},
+			},
+		},
+		{
+			name: "unknown function",
+			template: `apiVersion: v1
+kind: Service
+spec:
+  externalName: {{repl ConfigOptoin "hostname"}}
+`,
+			configGroups: configGroups,
+			expected: []TemplateError{
+				{
+					Name:       "service.yaml",
+					Line:       4,
+					Expression: `externalName: {{repl ConfigOptoin "hostname"}}`,
+					Message:    `function "ConfigOptoin" not defined`,
+				},
+			},
+		},
+		{
+			name: "unknown config items",
+			template: `apiVersion: v1
+kind: Service
+spec:
+  externalName: {{repl ConfigOption "host_name"}}
+  {{repl if ConfigOptionEquals "tls" "1"}}
+  ports: [443]
+  {{repl end}}
+`,
+			configGroups: configGroups,
+			expected: []TemplateError{
+				{
+					Name:       "service.yaml",
+					Line:       4,
+					Column:     24,
+					Expression: `ConfigOption "host_name"`,
+					Message:    "config item host_name is not defined",
+				},
+				{
+					Name:       "service.yaml",
+					Line:       5,
+					Column:     13,
+					Expression: `ConfigOptionEquals "tls" "1"`,
+					Message:    "config item tls is not defined",
+				},
+			},
+		},
+		{
+			name: "no config",
+			template: `apiVersion: v1
+kind: Service
+spec:
+  externalName: {{repl ConfigOption "host_name"}}
+`,
+			expected: []TemplateError{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, Lint("service.yaml", test.template, test.configGroups))
+		})
+	}
+}
@@ -3,8 +3,12 @@ package template
 import (
 	"bytes"
 	"crypto/rand"
+	"fmt"
 	"math/big"
+	"regexp"
 	"regexp/syntax"
+	"text/template"
+	"unicode/utf8"
 
 	"github.com/pkg/errors"
 )
@@ -36,6 +40,73 @@ func (ctx *StaticCtx) RandomString(length uint64, providedCharset ...string) str
 	return result
 }
 
+// templateFuncMap returns the functions that depend on the template that is rendered.
+// Random strings are kept in the generated values, so that rendering the template again
+// gives the same strings. A string with an explicit key, the third argument, is kept by
+// that key and is the same in every template that uses it. Other strings are kept by the
+// template name, the length and charset, and the order of the calls with the same length
+// and charset, so that adding a different call doesn't change the strings of the others
+func (ctx StaticCtx) templateFuncMap(name string) template.FuncMap {
+	generator := newGenerator(ctx.GeneratedValues)
+	calls := 0
+	callsByArgs := map[string]int{}
+
+	return template.FuncMap{
+		"RandomString": func(length uint64, providedCharset ...string) string {
+			charset := DefaultCharset
+			if len(providedCharset) >= 1 {
+				charset = providedCharset[0]
+			}
+
+			// strings were kept by the order of all calls in the template before
+			legacyKey := fmt.Sprintf("%s/%d", name, calls)
+			calls++
+
+			if len(providedCharset) >= 2 {
+				return generator.randomString(fmt.Sprintf("key/%s", providedCharset[1]), legacyKey, length, charset)
+			}
+
+			args := fmt.Sprintf("%d/%s", length, charset)
+			key := fmt.Sprintf("%s/%s/%d", name, args, callsByArgs[args])
+			callsByArgs[args]++
+
+			return generator.randomString(key, legacyKey, length, charset)
+		},
+	}
+}
+
+// randomString returns the random string kept for key. A string that is still kept by its
+// legacy key is moved to key, replacing one that was only just generated for this version.
+// A new one is generated when there is none, or when the kept one doesn't have the length
+// or charset that is asked for
+func (g generator) randomString(key string, legacyKey string, length uint64, charset string) string {
+	if legacy, ok := g.values.RandomStrings[legacyKey]; ok {
+		g.values.RandomStrings[key] = legacy
+		delete(g.values.RandomStrings, legacyKey)
+	}
+
+	if existing, ok := g.values.RandomStrings[key]; ok && isRandomString(existing, length, charset) {
+		return existing
+	}
+
+	ctx := &StaticCtx{}
+	generated := ctx.RandomString(length, charset)
+	if generated != "" {
+		g.values.RandomStrings[key] = generated
+	}
+
+	return generated
+}
+
+func isRandomString(value string, length uint64, charset string) bool {
+	if uint64(utf8.RuneCountInString(value)) != length {
+		return false
+	}
+
+	matched, err := regexp.MatchString(fmt.Sprintf("^(?:%s)*$", charset), value)
+	return err == nil && matched
+}
+
 func (ctx *StaticCtx) genString(w *bytes.Buffer, rx *syntax.Regexp) error {
 	switch rx.Op {
 	case syntax.OpCharClass:
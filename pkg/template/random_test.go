@@ -6,8 +6,9 @@ import (
 	"testing"
 	_ "unicode/utf8"
 
+	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
 	"github.com/stretchr/testify/assert"
-	_ "github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGenerateRandomString(t *testing.T) {
@@ -90,3 +91,95 @@ func TestGenerateRandomStringTemplates(t *testing.T) {
 		})
 	}
 }
+
+func TestRandomStringGeneratedValues(t *testing.T) {
+	req := require.New(t)
+
+	values := &kotsv1beta1.GeneratedValues{}
+	render := func(name string, text string) string {
+		builder := Builder{}
+		builder.AddCtx(StaticCtx{GeneratedValues: values})
+		rendered, err := builder.RenderTemplate(name, text)
+		req.NoError(err)
+		return rendered
+	}
+
+	first := render("secret.yaml", `{{repl RandomString 16}},{{repl RandomString 8 "[a-z]"}}`)
+	req.Len(values.RandomStrings, 2)
+	assert.Equal(t, values.RandomStrings["secret.yaml/16/[_A-Za-z0-9]/0"]+","+values.RandomStrings["secret.yaml/8/[a-z]/0"], first)
+
+	// rendering again keeps the strings
+	assert.Equal(t, first, render("secret.yaml", `{{repl RandomString 16}},{{repl RandomString 8 "[a-z]"}}`))
+
+	// a call that is added before them doesn't change them
+	added := render("secret.yaml", `{{repl RandomString 12}},{{repl RandomString 16}},{{repl RandomString 8 "[a-z]"}}`)
+	assert.Equal(t, first, added[13:])
+
+	// unless the length or charset changes
+	second := render("secret.yaml", `{{repl RandomString 16}},{{repl RandomString 8 "[0-9]"}}`)
+	assert.Equal(t, values.RandomStrings["secret.yaml/16/[_A-Za-z0-9]/0"], second[:16])
+	assert.Regexp(t, "^[0-9]{8}$", values.RandomStrings["secret.yaml/8/[0-9]/0"])
+
+	// a string with a key is the same in every template
+	keyed := render("secret.yaml", `{{repl RandomString 16 "[a-z]" "db-password"}}`)
+	assert.Equal(t, keyed, render("deployment.yaml", `{{repl RandomString 16 "[a-z]" "db-password"}}`))
+	assert.Equal(t, keyed, values.RandomStrings["key/db-password"])
+
+	// rotating them is removing them
+	values.RandomStrings = nil
+	assert.NotEqual(t, second, render("secret.yaml", `{{repl RandomString 16}},{{repl RandomString 8 "[0-9]"}}`))
+}
+
+func TestRandomStringLegacyKeys(t *testing.T) {
+	req := require.New(t)
+
+	// strings that were kept by the order of the calls in the template
+	values := &kotsv1beta1.GeneratedValues{
+		RandomStrings: map[string]string{
+			"secret.yaml/0": "abcdefghijklmnop",
+			"secret.yaml/1": "qrstuvwx",
+		},
+	}
+	builder := Builder{}
+	builder.AddCtx(StaticCtx{GeneratedValues: values})
+	rendered, err := builder.RenderTemplate("secret.yaml", `{{repl RandomString 16 "[a-z]"}},{{repl RandomString 8 "[a-z]"}}`)
+	req.NoError(err)
+
+	assert.Equal(t, "abcdefghijklmnop,qrstuvwx", rendered)
+	assert.Equal(t, map[string]string{
+		"secret.yaml/16/[a-z]/0": "abcdefghijklmnop",
+		"secret.yaml/8/[a-z]/0":  "qrstuvwx",
+	}, values.RandomStrings)
+}
+
+func TestRandomStringConfigDefault(t *testing.T) {
+	req := require.New(t)
+
+	configGroups := []kotsv1beta1.ConfigGroup{
+		{
+			Name: "database",
+			Items: []kotsv1beta1.ConfigItem{
+				{Name: "password", Type: "password", Default: `{{repl RandomString 20}}`},
+				{Name: "other_password", Type: "password", Default: `{{repl RandomString 20}}`},
+			},
+		},
+	}
+
+	values := &kotsv1beta1.GeneratedValues{}
+	newConfigCtx := func() *ConfigCtx {
+		builder := Builder{}
+		builder.AddCtx(StaticCtx{GeneratedValues: values})
+		configCtx, err := builder.NewConfigContext(configGroups, nil)
+		req.NoError(err)
+		return configCtx
+	}
+
+	first := newConfigCtx()
+	second := newConfigCtx()
+
+	assert.Len(t, first.ItemValues["password"], 20)
+	assert.Equal(t, first.ItemValues, second.ItemValues)
+	// the items don't share a value, even though their defaults are the same
+	assert.NotEqual(t, first.ItemValues["password"], first.ItemValues["other_password"])
+	assert.Equal(t, first.ItemValues["password"], values.RandomStrings["config/password/default/20/[_A-Za-z0-9]/0"])
+}
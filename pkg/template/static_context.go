@@ -12,6 +12,7 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/binary"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"net/url"
@@ -24,6 +25,7 @@ import (
 	"github.com/Masterminds/sprig"
 	units "github.com/docker/go-units"
 	"github.com/pkg/errors"
+	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
 	certUtil "k8s.io/client-go/util/cert"
 )
 
@@ -32,6 +34,9 @@ type Ctx interface {
 }
 
 type StaticCtx struct {
+	// GeneratedValues keeps the certificates and keys that templates generate, so that
+	// they are the same every time the templates are rendered
+	GeneratedValues *kotsv1beta1.GeneratedValues
 }
 
 func (ctx StaticCtx) FuncMap() template.FuncMap {
@@ -59,6 +64,12 @@ func (ctx StaticCtx) FuncMap() template.FuncMap {
 	sprigMap["HumanSize"] = ctx.humanSize
 	sprigMap["KubeSeal"] = ctx.kubeSeal
 
+	generator := newGenerator(ctx.GeneratedValues)
+	sprigMap["GenerateCA"] = generator.generateCA
+	sprigMap["GenerateCertFromCA"] = generator.generateCertFromCA
+	sprigMap["GenerateKeyPair"] = generator.generateKeyPair
+	sprigMap["Sha256"] = ctx.sha256
+
 	return sprigMap
 }
 
@@ -188,6 +199,12 @@ func (ctx StaticCtx) parseUint(str string, args ...int) uint64 {
 	return val
 }
 
+// sha256 returns the hex encoded sha256 sum of value
+func (ctx StaticCtx) sha256(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
 func (ctx StaticCtx) humanSize(size interface{}) string {
 	v := reflect.ValueOf(size)
 	return units.HumanSize(ctx.reflectToFloat(v))
@@ -8,6 +8,7 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/replicatedhq/kots/pkg/k8sutil"
+	kotslicense "github.com/replicatedhq/kots/pkg/license"
 	"github.com/replicatedhq/kots/pkg/logger"
 )
 
@@ -22,6 +23,9 @@ func UploadLicense(path string, uploadLicenseOptions UploadLicenseOptions) error
 	if err != nil {
 		return errors.Wrap(err, "failed to read license file")
 	}
+	if _, err := kotslicense.ParseLicense(b); err != nil {
+		return errors.Wrap(err, "failed to parse license")
+	}
 	license := string(b)
 
 	// Make sure we have a name or slug
@@ -9,11 +9,14 @@ import (
 )
 
 type FetchOptions struct {
-	HelmRepoName string
-	HelmRepoURI  string
-	HelmOptions  []string
-	LocalPath    string
-	License      *kotsv1beta1.License
+	HelmRepoName     string
+	HelmRepoURI      string
+	HelmRepoOptions  HelmRepoOptions
+	HelmCacheOptions HelmCacheOptions
+	HelmOptions      []string
+	HelmValuesFiles  []string
+	LocalPath        string
+	License          *kotsv1beta1.License
 }
 
 func FetchUpstream(upstreamURI string, fetchOptions *FetchOptions) (*Upstream, error) {
@@ -22,12 +25,18 @@ func FetchUpstream(upstreamURI string, fetchOptions *FetchOptions) (*Upstream, e
 		return nil, errors.Wrap(err, "download upstream failed")
 	}
 
+	if upstream.Type == "helm" {
+		if err := addHelmValues(upstream, fetchOptions); err != nil {
+			return nil, errors.Wrap(err, "failed to add helm values")
+		}
+	}
+
 	return upstream, nil
 }
 
 func downloadUpstream(upstreamURI string, fetchOptions *FetchOptions) (*Upstream, error) {
 	if !util.IsURL(upstreamURI) {
-		return readFilesFromPath(upstreamURI)
+		return readFilesFromPath(upstreamURI, fetchOptions.License)
 	}
 
 	u, err := url.ParseRequestURI(upstreamURI)
@@ -35,7 +44,7 @@ func downloadUpstream(upstreamURI string, fetchOptions *FetchOptions) (*Upstream
 		return nil, errors.Wrap(err, "parse request uri failed")
 	}
 	if u.Scheme == "helm" {
-		return downloadHelm(u, fetchOptions.HelmRepoURI)
+		return downloadHelm(u, fetchOptions.HelmRepoURI, fetchOptions.HelmRepoOptions, fetchOptions.HelmCacheOptions)
 	}
 	if u.Scheme == "replicated" {
 		return downloadReplicated(u, fetchOptions.LocalPath, fetchOptions.License)
@@ -1,13 +1,193 @@
 package upstream
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
 	"github.com/pkg/errors"
+	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
+	"gopkg.in/yaml.v2"
 )
 
-func readFilesFromPath(filepath string) (*Upstream, error) {
-	return nil, errors.New("not implemented")
+type chartMetadata struct {
+	Name    string `yaml:"name"`
+	Version string `yaml:"version"`
+}
+
+type overlySimpleAPIVersion struct {
+	APIVersion string `yaml:"apiVersion"`
+}
+
+// readFilesFromPath reads a local directory or a single file as an upstream. The upstream
+// type is detected from the content: a directory with a Chart.yaml is a helm chart, any
+// kots.io kinds make it a replicated release, and anything else is plain manifests
+func readFilesFromPath(upstreamPath string, license *kotsv1beta1.License) (*Upstream, error) {
+	absPath, err := filepath.Abs(upstreamPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get absolute path")
+	}
+
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to stat path")
+	}
+
+	files := []UpstreamFile{}
+	name := filepath.Base(absPath)
+	if info.IsDir() {
+		dirFiles, err := readFilesFromDir(absPath)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read dir")
+		}
+		files = dirFiles
+	} else {
+		content, err := ioutil.ReadFile(absPath)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read file")
+		}
+		files = append(files, UpstreamFile{
+			Path:    filepath.Base(absPath),
+			Content: content,
+		})
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+
+	updateCursor := hashUpstreamFiles(files)
+
+	switch getLocalUpstreamType(files) {
+	case "helm":
+		versionLabel := ""
+		for _, file := range files {
+			if file.Path != "Chart.yaml" {
+				continue
+			}
+
+			metadata := chartMetadata{}
+			if err := yaml.Unmarshal(file.Content, &metadata); err != nil {
+				return nil, errors.Wrap(err, "failed to unmarshal Chart.yaml")
+			}
+			if metadata.Name != "" {
+				name = metadata.Name
+			}
+			versionLabel = metadata.Version
+		}
+
+		return &Upstream{
+			URI:          upstreamPath,
+			Name:         name,
+			Type:         "helm",
+			Files:        files,
+			UpdateCursor: updateCursor,
+			VersionLabel: versionLabel,
+		}, nil
+
+	case "replicated":
+		release := &Release{
+			Manifests:    map[string][]byte{},
+			UpdateCursor: updateCursor,
+		}
+		for _, file := range files {
+			release.Manifests[file.Path] = file.Content
+		}
+
+		return releaseToUpstream(upstreamPath, release, license)
+	}
+
+	return &Upstream{
+		URI:          upstreamPath,
+		Name:         name,
+		Type:         "manifests",
+		Files:        files,
+		UpdateCursor: updateCursor,
+	}, nil
 }
 
 func readFilesFromURI(upstreamURI string) (*Upstream, error) {
 	return nil, errors.New("not implemented")
 }
+
+func readFilesFromDir(root string) ([]UpstreamFile, error) {
+	upstreamFiles := []UpstreamFile{}
+	err := filepath.Walk(root,
+		func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if info.IsDir() {
+				if info.Name() == ".git" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			contents, err := ioutil.ReadFile(p)
+			if err != nil {
+				return err
+			}
+
+			relPath, err := filepath.Rel(root, p)
+			if err != nil {
+				return err
+			}
+
+			upstreamFiles = append(upstreamFiles, UpstreamFile{
+				Path:    filepath.ToSlash(relPath),
+				Content: contents,
+			})
+
+			return nil
+		})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to walk dir")
+	}
+
+	return upstreamFiles, nil
+}
+
+func getLocalUpstreamType(files []UpstreamFile) string {
+	for _, file := range files {
+		if file.Path == "Chart.yaml" {
+			return "helm"
+		}
+	}
+
+	for _, file := range files {
+		for _, doc := range bytes.Split(file.Content, []byte("\n---")) {
+			o := overlySimpleAPIVersion{}
+			if err := yaml.Unmarshal(doc, &o); err != nil {
+				continue
+			}
+
+			if strings.HasPrefix(o.APIVersion, "kots.io/") {
+				return "replicated"
+			}
+		}
+	}
+
+	return "manifests"
+}
+
+// hashUpstreamFiles returns a digest of the paths and contents of all files,
+// independent of the order that they were read in
+func hashUpstreamFiles(files []UpstreamFile) string {
+	sorted := make([]UpstreamFile, len(files))
+	copy(sorted, files)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Path < sorted[j].Path
+	})
+
+	h := sha256.New()
+	for _, file := range sorted {
+		fmt.Fprintf(h, "%s\x00%d\x00", file.Path, len(file.Content))
+		h.Write(file.Content)
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
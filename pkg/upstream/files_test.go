@@ -0,0 +1,106 @@
+package upstream
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_readFilesFromPath(t *testing.T) {
+	tests := []struct {
+		name                 string
+		files                map[string]string
+		expectedType         string
+		expectedName         string
+		expectedVersionLabel string
+	}{
+		{
+			name: "manifests",
+			files: map[string]string{
+				"deployment.yaml":     "apiVersion: apps/v1\nkind: Deployment",
+				"nested/service.yaml": "apiVersion: v1\nkind: Service",
+			},
+			expectedType: "manifests",
+			expectedName: "manifests",
+		},
+		{
+			name: "helm chart",
+			files: map[string]string{
+				"Chart.yaml":                "name: my-chart\nversion: 1.2.3",
+				"values.yaml":               "replicas: 1",
+				"templates/deployment.yaml": "kind: Deployment",
+			},
+			expectedType:         "helm",
+			expectedName:         "my-chart",
+			expectedVersionLabel: "1.2.3",
+		},
+		{
+			name: "replicated release",
+			files: map[string]string{
+				"deployment.yaml": "apiVersion: apps/v1\nkind: Deployment",
+				"app.yaml":        "apiVersion: kots.io/v1beta1\nkind: Application\nmetadata:\n  name: my-app",
+			},
+			expectedType: "replicated",
+			expectedName: "my-app",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := require.New(t)
+
+			tmpDir, err := ioutil.TempDir("", "kots")
+			req.NoError(err)
+			defer os.RemoveAll(tmpDir)
+
+			root := filepath.Join(tmpDir, test.expectedType)
+			for name, content := range test.files {
+				p := filepath.Join(root, name)
+				req.NoError(os.MkdirAll(filepath.Dir(p), 0755))
+				req.NoError(ioutil.WriteFile(p, []byte(content), 0644))
+			}
+
+			u, err := readFilesFromPath(root, nil)
+			req.NoError(err)
+
+			assert.Equal(t, test.expectedType, u.Type)
+			assert.Equal(t, test.expectedName, u.Name)
+			assert.Equal(t, test.expectedVersionLabel, u.VersionLabel)
+			assert.NotEmpty(t, u.UpdateCursor)
+			for name := range test.files {
+				assert.Contains(t, u.Files, UpstreamFile{Path: name, Content: []byte(test.files[name])})
+			}
+
+			again, err := readFilesFromPath(root, nil)
+			req.NoError(err)
+			assert.Equal(t, u.UpdateCursor, again.UpdateCursor)
+		})
+	}
+}
+
+func Test_readFilesFromPathSingleFile(t *testing.T) {
+	req := require.New(t)
+
+	tmpDir, err := ioutil.TempDir("", "kots")
+	req.NoError(err)
+	defer os.RemoveAll(tmpDir)
+
+	p := filepath.Join(tmpDir, "app.yaml")
+	req.NoError(ioutil.WriteFile(p, []byte("apiVersion: v1\nkind: Service"), 0644))
+
+	u, err := readFilesFromPath(p, nil)
+	req.NoError(err)
+	assert.Equal(t, "manifests", u.Type)
+	assert.Equal(t, "app", u.Name)
+	assert.Equal(t, []UpstreamFile{{Path: "app.yaml", Content: []byte("apiVersion: v1\nkind: Service")}}, u.Files)
+
+	before := u.UpdateCursor
+	req.NoError(ioutil.WriteFile(p, []byte("apiVersion: v1\nkind: ConfigMap"), 0644))
+	u, err = readFilesFromPath(p, nil)
+	req.NoError(err)
+	assert.NotEqual(t, before, u.UpdateCursor)
+}
@@ -1,9 +1,149 @@
 package upstream
 
 import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
 	"github.com/pkg/errors"
 )
 
+type GitUpstream struct {
+	RepoURI string
+	Ref     string
+	Subdir  string
+}
+
 func downloadGit(gitURI string) (*Upstream, error) {
-	return nil, errors.New("not implemented")
+	gitUpstream, err := parseGitURI(gitURI)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse git uri")
+	}
+
+	cloneDir, err := ioutil.TempDir("", "kots")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create temp dir for clone")
+	}
+	defer os.RemoveAll(cloneDir)
+
+	if _, err := runGit("", "clone", "--quiet", "--no-checkout", "--", gitUpstream.RepoURI, cloneDir); err != nil {
+		return nil, errors.Wrap(err, "failed to clone repo")
+	}
+
+	ref := gitUpstream.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	if _, err := runGit(cloneDir, "checkout", "--quiet", ref, "--"); err != nil {
+		return nil, errors.Wrapf(err, "failed to checkout %q", ref)
+	}
+
+	commit, err := runGit(cloneDir, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get commit sha")
+	}
+
+	files, err := readGitFiles(cloneDir, gitUpstream.Subdir)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read files from repo")
+	}
+
+	versionLabel := gitUpstream.Ref
+	if versionLabel == "" {
+		versionLabel = commit[:7]
+	}
+
+	upstream := &Upstream{
+		URI:          gitURI,
+		Name:         gitUpstream.name(),
+		Type:         "git",
+		Files:        files,
+		UpdateCursor: commit,
+		VersionLabel: versionLabel,
+	}
+
+	return upstream, nil
+}
+
+// parseGitURI splits a uri in the form git://host/org/repo@ref//subdir into the
+// uri to clone, the ref to checkout and the subdirectory to read. A uri without
+// a host (git:///path/to/repo) is cloned from the local filesystem.
+func parseGitURI(gitURI string) (*GitUpstream, error) {
+	if !strings.HasPrefix(gitURI, "git://") {
+		return nil, errors.Errorf("not a git uri: %q", gitURI)
+	}
+	rest := strings.TrimPrefix(gitURI, "git://")
+
+	gitUpstream := GitUpstream{}
+
+	if idx := strings.Index(rest, "//"); idx != -1 {
+		gitUpstream.Subdir = strings.Trim(rest[idx+2:], "/")
+		rest = rest[:idx]
+	}
+
+	firstSlash := strings.Index(rest, "/")
+	if firstSlash == -1 {
+		return nil, errors.New("git uri is missing a repository path")
+	}
+	if idx := strings.LastIndex(rest, "@"); idx > firstSlash {
+		gitUpstream.Ref = rest[idx+1:]
+		rest = rest[:idx]
+	}
+	if strings.HasPrefix(gitUpstream.Ref, "-") {
+		return nil, errors.Errorf("invalid git ref %q", gitUpstream.Ref)
+	}
+
+	host := rest[:firstSlash]
+	repoPath := rest[firstSlash:]
+	if strings.Trim(repoPath, "/") == "" {
+		return nil, errors.New("git uri is missing a repository path")
+	}
+
+	if host == "" {
+		gitUpstream.RepoURI = repoPath
+	} else {
+		gitUpstream.RepoURI = "git://" + rest
+	}
+
+	return &gitUpstream, nil
+}
+
+func (g GitUpstream) name() string {
+	if g.Subdir != "" {
+		return path.Base(g.Subdir)
+	}
+
+	return strings.TrimSuffix(path.Base(g.RepoURI), ".git")
+}
+
+func readGitFiles(cloneDir string, subdir string) ([]UpstreamFile, error) {
+	root := filepath.Join(cloneDir, filepath.FromSlash(subdir))
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to stat %q in repo", subdir)
+	}
+	if !info.IsDir() {
+		return nil, errors.Errorf("%q is not a directory", subdir)
+	}
+
+	return readFilesFromDir(root)
+}
+
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", errors.Wrap(err, strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
 }
@@ -0,0 +1,160 @@
+package upstream
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseGitURI(t *testing.T) {
+	tests := []struct {
+		name     string
+		uri      string
+		expected GitUpstream
+	}{
+		{
+			name: "repo only",
+			uri:  "git://github.com/org/repo",
+			expected: GitUpstream{
+				RepoURI: "git://github.com/org/repo",
+			},
+		},
+		{
+			name: "repo with ref",
+			uri:  "git://github.com/org/repo@v1.0.0",
+			expected: GitUpstream{
+				RepoURI: "git://github.com/org/repo",
+				Ref:     "v1.0.0",
+			},
+		},
+		{
+			name: "repo with ref and subdir",
+			uri:  "git://github.com/org/repo@feature/branch//deploy/manifests",
+			expected: GitUpstream{
+				RepoURI: "git://github.com/org/repo",
+				Ref:     "feature/branch",
+				Subdir:  "deploy/manifests",
+			},
+		},
+		{
+			name: "user in host",
+			uri:  "git://git@github.com/org/repo//deploy",
+			expected: GitUpstream{
+				RepoURI: "git://git@github.com/org/repo",
+				Subdir:  "deploy",
+			},
+		},
+		{
+			name: "local repo",
+			uri:  "git:///tmp/repo.git@master",
+			expected: GitUpstream{
+				RepoURI: "/tmp/repo.git",
+				Ref:     "master",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := require.New(t)
+
+			actual, err := parseGitURI(test.uri)
+			req.NoError(err)
+			assert.Equal(t, test.expected, *actual)
+		})
+	}
+}
+
+func Test_parseGitURIErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		uri         string
+		expectedErr string
+	}{
+		{
+			name:        "not git",
+			uri:         "https://github.com/org/repo",
+			expectedErr: "not a git uri",
+		},
+		{
+			name:        "missing repository path",
+			uri:         "git://github.com",
+			expectedErr: "missing a repository path",
+		},
+		{
+			name:        "ref is an option",
+			uri:         "git://github.com/org/repo@--orphan=x",
+			expectedErr: `invalid git ref "--orphan=x"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseGitURI(test.uri)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.expectedErr)
+		})
+	}
+}
+
+func Test_downloadGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	req := require.New(t)
+
+	tmpDir, err := ioutil.TempDir("", "kots-git")
+	req.NoError(err)
+	defer os.RemoveAll(tmpDir)
+
+	workDir := filepath.Join(tmpDir, "work")
+	bareDir := filepath.Join(tmpDir, "app.git")
+	req.NoError(os.MkdirAll(filepath.Join(workDir, "manifests"), 0755))
+
+	git := func(dir string, args ...string) string {
+		args = append([]string{"-c", "user.name=kots", "-c", "user.email=kots@example.com"}, args...)
+		out, err := runGit(dir, args...)
+		req.NoError(err)
+		return out
+	}
+
+	git(workDir, "init", "--quiet")
+	req.NoError(ioutil.WriteFile(filepath.Join(workDir, "README.md"), []byte("readme"), 0644))
+	req.NoError(ioutil.WriteFile(filepath.Join(workDir, "manifests", "deployment.yaml"), []byte("kind: Deployment"), 0644))
+	git(workDir, "add", ".")
+	git(workDir, "commit", "--quiet", "-m", "first")
+	git(workDir, "tag", "v1")
+	firstCommit := git(workDir, "rev-parse", "HEAD")
+
+	req.NoError(ioutil.WriteFile(filepath.Join(workDir, "manifests", "service.yaml"), []byte("kind: Service"), 0644))
+	git(workDir, "add", ".")
+	git(workDir, "commit", "--quiet", "-m", "second")
+	secondCommit := git(workDir, "rev-parse", "HEAD")
+
+	git(tmpDir, "clone", "--quiet", "--bare", workDir, bareDir)
+
+	u, err := downloadGit("git://" + bareDir + "@v1//manifests")
+	req.NoError(err)
+	assert.Equal(t, "git", u.Type)
+	assert.Equal(t, "manifests", u.Name)
+	assert.Equal(t, firstCommit, u.UpdateCursor)
+	assert.Equal(t, "v1", u.VersionLabel)
+	assert.Equal(t, []UpstreamFile{{Path: "deployment.yaml", Content: []byte("kind: Deployment")}}, u.Files)
+
+	u, err = downloadGit("git://" + bareDir + "@" + secondCommit + "//manifests")
+	req.NoError(err)
+	assert.Equal(t, secondCommit, u.UpdateCursor)
+	assert.Len(t, u.Files, 2)
+
+	u, err = downloadGit("git://" + bareDir)
+	req.NoError(err)
+	assert.Equal(t, "app", u.Name)
+	assert.Equal(t, secondCommit, u.UpdateCursor)
+	assert.Len(t, u.Files, 3)
+}
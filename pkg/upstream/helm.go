@@ -4,191 +4,345 @@ import (
 	"archive/tar"
 	"bytes"
 	"compress/gzip"
-	"fmt"
 	"io"
-	"io/ioutil"
 	"net/url"
 	"os"
 	"path"
-	"path/filepath"
 	"strings"
 
-	"github.com/Masterminds/semver"
+	semver "github.com/Masterminds/semver/v3"
+	"github.com/ghodss/yaml"
 	"github.com/pkg/errors"
 	"github.com/replicatedhq/kots/pkg/util"
-	"k8s.io/helm/cmd/helm/search"
-	"k8s.io/helm/pkg/downloader"
+	"k8s.io/helm/pkg/chartutil"
 	"k8s.io/helm/pkg/getter"
-	"k8s.io/helm/pkg/helm/environment"
-	"k8s.io/helm/pkg/helm/helmpath"
 	"k8s.io/helm/pkg/repo"
 )
 
-func downloadHelm(u *url.URL, repoURI string) (*Upstream, error) {
-	repoName, chartName, chartVersion, err := parseHelmURL(u)
+func downloadHelm(u *url.URL, repoURI string, repoOptions HelmRepoOptions, cacheOptions HelmCacheOptions) (*Upstream, error) {
+	repoName, chartName, versionConstraint, err := parseHelmURL(u)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to parse helm uri")
 	}
 
+	repoURI, repoOptions, err = parseHelmRepoURI(repoURI, repoOptions)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse helm repo uri")
+	}
+
 	if repoURI == "" {
-		repoURI = getKnownHelmRepoURI(repoName)
+		knownRepo, err := getKnownHelmRepo(repoName)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get known helm repo")
+		}
+		if knownRepo != nil {
+			repoURI = knownRepo.URI
+			// options passed for this pull replace the ones stored with the repo
+			if repoOptions.isEmpty() {
+				repoOptions = knownRepo.options()
+			}
+		}
 	}
 
 	if repoURI == "" {
 		return nil, errors.New("unknown helm repo uri, try passing the repo uri")
 	}
 
-	helmHome, err := ioutil.TempDir("", "kots")
+	cache := helmCache{cacheOptions}
+
+	// dependencies can be in the other repos that were added
+	helmRepos, err := loadKnownHelmRepos()
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to create temporary helm home")
+		return nil, errors.Wrap(err, "failed to load known helm repos")
 	}
-	defer os.RemoveAll(helmHome)
-	if err := os.MkdirAll(filepath.Join(helmHome, "repository"), 0755); err != nil {
-		return nil, errors.Wrap(err, "failed to make directory for helm home")
-	}
-	reposFile := filepath.Join(helmHome, "repository", "repositories.yaml")
+	getters := repoOptions.getters(repoURI, helmRepos)
 
-	repoIndexFile, err := ioutil.TempFile("", "index")
+	index, err := cache.getIndex(repoURI, getters)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to create temporary index file")
+		return nil, errors.Wrap(err, "failed to get repo index")
 	}
-	defer os.Remove(repoIndexFile.Name())
 
-	cacheIndexFile, err := ioutil.TempFile("", "cache")
+	chartVersions := index.Entries[chartName]
+	chartVersion, err := resolveHelmChartVersion(chartVersions, versionConstraint)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to create cache index file")
+		return nil, errors.Wrapf(err, "failed to resolve version of chart %q", chartName)
 	}
-	defer os.Remove(cacheIndexFile.Name())
 
-	repoYAML := `apiVersion: v1
-generated: "2019-05-29T14:31:58.906598702Z"
-repositories: []`
-	if err := ioutil.WriteFile(reposFile, []byte(repoYAML), 0644); err != nil {
-		return nil, err
+	for _, cv := range chartVersions {
+		if cv.GetVersion() != chartVersion {
+			continue
+		}
+
+		content, err := cache.getChart(repoURI, cv, getters)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to download chart")
+		}
+
+		files, err := readTarGzContent(content)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read chart archive")
+		}
+
+		files, err = fetchHelmDependencies(files, getters, cache)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to fetch chart dependencies")
+		}
+
+		upstream := &Upstream{
+			URI:          u.RequestURI(),
+			Name:         chartName,
+			Type:         "helm",
+			Files:        files,
+			UpdateCursor: chartVersion,
+			VersionLabel: chartVersion,
+		}
+
+		return upstream, nil
 	}
 
-	c := repo.Entry{
-		Name:  repoName,
-		Cache: repoIndexFile.Name(),
-		URL:   repoURI,
+	return nil, errors.New("chart version not found")
+}
+
+func parseHelmURL(u *url.URL) (string, string, string, error) {
+	repo := u.Host
+	chartName := strings.TrimLeft(u.Path, "/")
+	chartVersion := ""
+
+	chartAndVersion := strings.Split(chartName, "@")
+	if len(chartAndVersion) > 1 {
+		chartName = chartAndVersion[0]
+		chartVersion = chartAndVersion[1]
+
+		if _, err := semver.NewConstraint(chartVersion); err != nil {
+			return "", "", "", errors.Wrapf(err, "invalid chart version %q", chartVersion)
+		}
 	}
-	r, err := repo.NewChartRepository(&c, getter.All(environment.EnvSettings{}))
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to create chart repository")
+
+	return repo, chartName, chartVersion, nil
+}
+
+// resolveHelmChartVersion returns the highest chart version that satisfies the version
+// constraint, or the highest version when there is no constraint. Prereleases only match
+// when they are asked for by including a prerelease in the constraint, e.g. ">=1.0.0-0"
+func resolveHelmChartVersion(chartVersions []*repo.ChartVersion, versionConstraint string) (string, error) {
+	if versionConstraint == "" {
+		versionConstraint = "*"
 	}
-	if err := r.DownloadIndexFile(cacheIndexFile.Name()); err != nil {
-		return nil, errors.Wrap(err, "failed to download index file")
+
+	// an exact version is used as is, even if it's not valid semver
+	for _, chartVersion := range chartVersions {
+		if chartVersion.GetVersion() == versionConstraint {
+			return versionConstraint, nil
+		}
 	}
 
-	rf, err := repo.LoadRepositoriesFile(reposFile)
+	constraint, err := semver.NewConstraint(versionConstraint)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to load repositories file")
+		return "", errors.Wrapf(err, "failed to parse version constraint %q", versionConstraint)
 	}
-	rf.Update(&c)
 
-	i := search.NewIndex()
-	for _, re := range rf.Repositories {
-		n := re.Name
-		ind, err := repo.LoadIndexFile(repoIndexFile.Name())
+	var highestVersion *semver.Version
+	highestChartVersion := ""
+	for _, chartVersion := range chartVersions {
+		v, err := semver.NewVersion(chartVersion.GetVersion())
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to load index file")
+			continue
 		}
 
-		i.AddRepo(n, ind, true)
+		if !constraint.Check(v) {
+			continue
+		}
+
+		if highestVersion == nil || v.GreaterThan(highestVersion) {
+			highestVersion = v
+			highestChartVersion = chartVersion.GetVersion()
+		}
 	}
 
-	if chartVersion == "" {
-		highestChartVersion := semver.MustParse("0.0.0")
-		for _, result := range i.All() {
-			if result.Chart.GetName() != chartName {
-				continue
-			}
+	if highestVersion == nil {
+		return "", errors.Errorf("no chart version matches %q", versionConstraint)
+	}
 
-			v, err := semver.NewVersion(result.Chart.GetVersion())
-			if err != nil {
-				return nil, errors.Wrap(err, "unable to parse chart version")
-			}
+	return highestChartVersion, nil
+}
 
-			if v.GreaterThan(highestChartVersion) {
-				highestChartVersion = v
-			}
+// fetchHelmDependencies downloads the dependencies declared in requirements.yaml
+// that are not already vendored in the charts directory, and adds them as archives
+// in the charts directory. Dependencies in the same repo as the chart are fetched with
+// the repo credentials from the getters
+func fetchHelmDependencies(files []UpstreamFile, getters getter.Providers, cache helmCache) ([]UpstreamFile, error) {
+	var requirementsContent []byte
+	var lockContent []byte
+	for _, file := range files {
+		if file.Path == "requirements.yaml" {
+			requirementsContent = file.Content
+		} else if file.Path == "requirements.lock" {
+			lockContent = file.Content
 		}
+	}
 
-		chartVersion = highestChartVersion.String()
+	if requirementsContent == nil {
+		return files, nil
 	}
 
-	for _, result := range i.All() {
-		if result.Chart.GetName() != chartName {
-			continue
+	requirements := chartutil.Requirements{}
+	if err := yaml.Unmarshal(requirementsContent, &requirements); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal requirements")
+	}
+
+	// prefer the versions in the lock file, when there is one
+	lockedVersions := map[string]string{}
+	if lockContent != nil {
+		lock := chartutil.RequirementsLock{}
+		if err := yaml.Unmarshal(lockContent, &lock); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal requirements lock")
+		}
+		for _, dependency := range lock.Dependencies {
+			lockedVersions[dependency.Name] = dependency.Version
 		}
+	}
 
-		if result.Chart.GetVersion() != chartVersion {
+	for _, dependency := range requirements.Dependencies {
+		if isHelmDependencyVendored(files, dependency.Name) {
 			continue
 		}
 
-		dl := downloader.ChartDownloader{
-			HelmHome: helmpath.Home(helmHome),
-			Out:      os.Stdout,
-			Getters:  getter.All(environment.EnvSettings{}),
+		repoURI, err := getHelmDependencyRepoURI(dependency.Repository)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get repo for dependency %q", dependency.Name)
+		}
+		if repoURI == "" {
+			return nil, errors.Errorf("unable to find repo %q for dependency %q", dependency.Repository, dependency.Name)
+		}
+
+		version := dependency.Version
+		if lockedVersion, ok := lockedVersions[dependency.Name]; ok {
+			version = lockedVersion
 		}
 
-		archiveDir, err := ioutil.TempDir("", "archive")
+		index, err := cache.getIndex(repoURI, getters)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to create archive directory for chart")
+			return nil, errors.Wrapf(err, "failed to get index for dependency %q", dependency.Name)
 		}
-		defer os.RemoveAll(archiveDir)
 
-		chartRef, err := repo.FindChartInRepoURL(repoURI, result.Chart.GetName(), chartVersion, "", "", "", getter.All(environment.EnvSettings{}))
+		chartVersions := index.Entries[dependency.Name]
+		resolvedVersion, err := resolveHelmChartVersion(chartVersions, version)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to find chart in repo url")
+			return nil, errors.Wrapf(err, "failed to resolve version of dependency %q", dependency.Name)
+		}
+
+		var chartVersion *repo.ChartVersion
+		for _, cv := range chartVersions {
+			if cv.GetVersion() == resolvedVersion {
+				chartVersion = cv
+				break
+			}
 		}
 
-		_, _, err = dl.DownloadTo(chartRef, result.Chart.GetVersion(), archiveDir)
+		content, err := cache.getChart(repoURI, chartVersion, getters)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to download chart")
+			return nil, errors.Wrapf(err, "failed to download dependency %q", dependency.Name)
 		}
 
-		files, err := readTarGz(path.Join(archiveDir, fmt.Sprintf("%s-%s.tgz", chartName, chartVersion)))
+		chartURL, err := resolveChartURL(repoURI, chartVersion)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to read chart archive")
+			return nil, errors.Wrap(err, "failed to resolve chart url")
+		}
+		u, err := url.Parse(chartURL)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse chart url")
 		}
 
-		upstream := &Upstream{
-			URI:          u.RequestURI(),
-			Name:         chartName,
-			Type:         "helm",
-			Files:        files,
-			UpdateCursor: chartVersion,
-			VersionLabel: chartVersion,
+		files = append(files, UpstreamFile{
+			Path:    path.Join("charts", path.Base(u.Path)),
+			Content: content,
+		})
+	}
+
+	return files, nil
+}
+
+func isHelmDependencyVendored(files []UpstreamFile, name string) bool {
+	for _, file := range files {
+		if file.Path == path.Join("charts", name, "Chart.yaml") {
+			return true
 		}
 
-		return upstream, nil
+		d, f := path.Split(file.Path)
+		if d != "charts/" || !strings.HasPrefix(f, name+"-") || !strings.HasSuffix(f, ".tgz") {
+			continue
+		}
+
+		// the remainder must be a version, so that "redis" doesn't match "redis-ha-1.0.0.tgz"
+		version := strings.TrimSuffix(strings.TrimPrefix(f, name+"-"), ".tgz")
+		if _, err := semver.NewVersion(version); err == nil {
+			return true
+		}
 	}
 
-	return nil, errors.New("chart version not found")
+	return false
 }
 
-func parseHelmURL(u *url.URL) (string, string, string, error) {
-	repo := u.Host
-	chartName := strings.TrimLeft(u.Path, "/")
-	chartVersion := ""
+// getHelmDependencyRepoURI returns the repo uri for a repository in requirements.yaml,
+// resolving the "@name" and "alias:name" forms to known repos
+func getHelmDependencyRepoURI(repository string) (string, error) {
+	repoName := ""
+	if strings.HasPrefix(repository, "@") {
+		repoName = strings.TrimPrefix(repository, "@")
+	} else if strings.HasPrefix(repository, "alias:") {
+		repoName = strings.TrimPrefix(repository, "alias:")
+	} else if strings.HasPrefix(repository, "http://") || strings.HasPrefix(repository, "https://") {
+		return repository, nil
+	} else {
+		return "", nil
+	}
 
-	chartAndVersion := strings.Split(chartName, "@")
-	if len(chartAndVersion) > 1 {
-		chartName = chartAndVersion[0]
-		chartVersion = chartAndVersion[1]
+	knownRepo, err := getKnownHelmRepo(repoName)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get known helm repo")
+	}
+	if knownRepo == nil {
+		return "", nil
 	}
 
-	return repo, chartName, chartVersion, nil
+	return knownRepo.URI, nil
+}
+
+// getKnownHelmRepo returns the repo with the name from the repos file, falling back to
+// the built in known repos, or nil if there is no repo with the name
+func getKnownHelmRepo(repoName string) (*HelmRepo, error) {
+	helmRepos, err := loadKnownHelmRepos()
+	if err != nil {
+		return nil, err
+	}
+
+	if helmRepo := helmRepos.Get(repoName); helmRepo != nil {
+		return helmRepo, nil
+	}
+
+	if uri, ok := KnownRepos[repoName]; ok {
+		return &HelmRepo{
+			Name: repoName,
+			URI:  uri,
+		}, nil
+	}
+
+	return nil, nil
 }
 
-func getKnownHelmRepoURI(repoName string) string {
-	val, ok := KnownRepos[repoName]
-	if !ok {
-		return ""
+// loadKnownHelmRepos reads the repos file in the home directory
+func loadKnownHelmRepos() (*HelmRepos, error) {
+	filename, err := HelmReposFilename()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get repos filename")
 	}
 
-	return val
+	helmRepos, err := LoadHelmRepos(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load repos")
+	}
+
+	return helmRepos, nil
 }
 
 func readTarGz(source string) ([]UpstreamFile, error) {
@@ -198,12 +352,24 @@ func readTarGz(source string) ([]UpstreamFile, error) {
 	}
 	defer f.Close()
 
-	gzf, err := gzip.NewReader(f)
+	return readTarGzReader(f)
+}
+
+func readTarGzContent(content []byte) ([]UpstreamFile, error) {
+	return readTarGzReader(bytes.NewReader(content))
+}
+
+func readTarGzReader(r io.Reader) ([]UpstreamFile, error) {
+	gzf, err := gzip.NewReader(r)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create gzip reader")
 	}
 
-	tarReader := tar.NewReader(gzf)
+	return readTarReader(gzf)
+}
+
+func readTarReader(r io.Reader) ([]UpstreamFile, error) {
+	tarReader := tar.NewReader(r)
 
 	upstreamFiles := []UpstreamFile{}
 	for {
@@ -216,10 +382,13 @@ func readTarGz(source string) ([]UpstreamFile, error) {
 			return nil, errors.Wrap(err, "failed to advance in tar archive")
 		}
 
-		name := header.Name
-
 		switch header.Typeflag {
 		case tar.TypeReg:
+			name, err := cleanArchivePath(header.Name)
+			if err != nil {
+				return nil, errors.Wrap(err, "invalid file in tar archive")
+			}
+
 			buf := new(bytes.Buffer)
 			_, err = buf.ReadFrom(tarReader)
 			if err != nil {
@@ -236,33 +405,48 @@ func readTarGz(source string) ([]UpstreamFile, error) {
 		}
 	}
 
-	// remove any common prefix from all files
-	if len(upstreamFiles) > 0 {
-		firstFileDir, _ := path.Split(upstreamFiles[0].Path)
-		commonPrefix := strings.Split(firstFileDir, string(os.PathSeparator))
+	return removeCommonPrefix(upstreamFiles), nil
+}
+
+// cleanArchivePath cleans the path of a file in an archive, and rejects paths that
+// would be outside of the directory that the archive is extracted to
+func cleanArchivePath(name string) (string, error) {
+	cleaned := path.Clean(name)
+	if path.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", errors.Errorf("path %q is outside of the archive", name)
+	}
+
+	return cleaned, nil
+}
 
-		for _, file := range upstreamFiles {
-			d, _ := path.Split(file.Path)
-			dirs := strings.Split(d, string(os.PathSeparator))
+// removeCommonPrefix removes any common leading directories from all files
+func removeCommonPrefix(upstreamFiles []UpstreamFile) []UpstreamFile {
+	if len(upstreamFiles) == 0 {
+		return upstreamFiles
+	}
 
-			commonPrefix = util.CommonSlicePrefix(commonPrefix, dirs)
+	firstFileDir, _ := path.Split(upstreamFiles[0].Path)
+	commonPrefix := strings.Split(firstFileDir, string(os.PathSeparator))
 
-		}
+	for _, file := range upstreamFiles {
+		d, _ := path.Split(file.Path)
+		dirs := strings.Split(d, string(os.PathSeparator))
+
+		commonPrefix = util.CommonSlicePrefix(commonPrefix, dirs)
 
-		cleanedUpstreamFiles := []UpstreamFile{}
-		for _, file := range upstreamFiles {
-			d, f := path.Split(file.Path)
-			d2 := strings.Split(d, string(os.PathSeparator))
+	}
 
-			cleanedUpstreamFile := file
-			d2 = d2[len(commonPrefix):]
-			cleanedUpstreamFile.Path = path.Join(path.Join(d2...), f)
+	cleanedUpstreamFiles := []UpstreamFile{}
+	for _, file := range upstreamFiles {
+		d, f := path.Split(file.Path)
+		d2 := strings.Split(d, string(os.PathSeparator))
 
-			cleanedUpstreamFiles = append(cleanedUpstreamFiles, cleanedUpstreamFile)
-		}
+		cleanedUpstreamFile := file
+		d2 = d2[len(commonPrefix):]
+		cleanedUpstreamFile.Path = path.Join(path.Join(d2...), f)
 
-		upstreamFiles = cleanedUpstreamFiles
+		cleanedUpstreamFiles = append(cleanedUpstreamFiles, cleanedUpstreamFile)
 	}
 
-	return upstreamFiles, nil
+	return cleanedUpstreamFiles
 }
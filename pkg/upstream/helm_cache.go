@@ -0,0 +1,260 @@
+package upstream
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	"k8s.io/helm/pkg/getter"
+	"k8s.io/helm/pkg/repo"
+)
+
+const DefaultHelmIndexTTL = time.Hour
+
+// HelmCacheOptions configure the cache of helm repo indexes and chart archives
+type HelmCacheOptions struct {
+	// Dir is the cache directory. Nothing is cached when it's empty
+	Dir string
+	// IndexTTL is how long a cached repo index is used before it's downloaded again
+	IndexTTL time.Duration
+	// Offline only reads from the cache, and fails when something is not cached
+	Offline bool
+}
+
+// helmCache stores content by its sha256 digest in blobs/sha256, and the digest of the
+// content last downloaded from each url in refs
+type helmCache struct {
+	HelmCacheOptions
+}
+
+type helmCacheRef struct {
+	URL       string    `json:"url"`
+	Digest    string    `json:"digest"`
+	FetchedAt time.Time `json:"fetchedAt"`
+}
+
+// getIndex returns the index of the repo, downloading it when it's not cached or the
+// cached index is older than the index ttl
+func (c helmCache) getIndex(repoURI string, getters getter.Providers) (*repo.IndexFile, error) {
+	indexURL := strings.TrimSuffix(repoURI, "/") + "/index.yaml"
+	content, err := c.get(indexURL, "", c.IndexTTL, getters)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get index")
+	}
+
+	index := repo.IndexFile{}
+	if err := yaml.Unmarshal(content, &index); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal index")
+	}
+	if index.APIVersion == "" {
+		return nil, errors.Errorf("%s is not a valid chart repository index", indexURL)
+	}
+	index.SortEntries()
+
+	return &index, nil
+}
+
+// getChart returns the chart archive, verified against the digest in the index when
+// there is one. Chart versions don't change, so cached charts never expire
+func (c helmCache) getChart(repoURI string, chartVersion *repo.ChartVersion, getters getter.Providers) ([]byte, error) {
+	chartURL, err := resolveChartURL(repoURI, chartVersion)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to resolve chart url")
+	}
+
+	digest := strings.TrimPrefix(chartVersion.Digest, "sha256:")
+	content, err := c.get(chartURL, digest, 0, getters)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get chart")
+	}
+
+	return content, nil
+}
+
+// resolveChartURL returns the url of the chart archive, relative to the repo when the
+// index has a relative url
+func resolveChartURL(repoURI string, chartVersion *repo.ChartVersion) (string, error) {
+	if len(chartVersion.URLs) == 0 {
+		return "", errors.Errorf("chart %s version %s has no urls in the repo index", chartVersion.GetName(), chartVersion.GetVersion())
+	}
+
+	return repo.ResolveReferenceURL(repoURI, chartVersion.URLs[0])
+}
+
+// get returns the content at the url from the cache, downloading it when it's not cached
+// or the cached content is older than the ttl. A ttl of 0 never expires. When a digest is
+// given, cached content is found by the digest and downloaded content must match it
+func (c helmCache) get(u string, digest string, ttl time.Duration, getters getter.Providers) ([]byte, error) {
+	if c.Dir != "" {
+		content, fetchedAt, err := c.read(u, digest)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read from cache")
+		}
+		if content != nil && (c.Offline || ttl == 0 || time.Since(fetchedAt) < ttl) {
+			return content, nil
+		}
+	}
+
+	if c.Offline {
+		return nil, errors.Errorf("%s is not cached, and cannot be downloaded in offline mode", u)
+	}
+
+	content, err := fetchHelmURL(u, getters)
+	if err != nil {
+		return nil, err
+	}
+
+	if digest != "" && sha256Digest(content) != digest {
+		return nil, errors.Errorf("digest of %s does not match the repo index", u)
+	}
+
+	if c.Dir != "" {
+		if err := c.write(u, content); err != nil {
+			return nil, errors.Wrap(err, "failed to write to cache")
+		}
+	}
+
+	return content, nil
+}
+
+// read returns the cached content for the digest, or for the url when there is no digest,
+// and when it was downloaded. Content that no longer matches its digest is ignored
+func (c helmCache) read(u string, digest string) ([]byte, time.Time, error) {
+	fetchedAt := time.Time{}
+	if digest == "" {
+		ref, err := c.readRef(u)
+		if err != nil {
+			return nil, fetchedAt, errors.Wrap(err, "failed to read ref")
+		}
+		if ref == nil {
+			return nil, fetchedAt, nil
+		}
+		digest = ref.Digest
+		fetchedAt = ref.FetchedAt
+	}
+
+	content, err := ioutil.ReadFile(c.blobPath(digest))
+	if os.IsNotExist(err) {
+		return nil, fetchedAt, nil
+	}
+	if err != nil {
+		return nil, fetchedAt, errors.Wrap(err, "failed to read blob")
+	}
+
+	if sha256Digest(content) != digest {
+		return nil, fetchedAt, nil
+	}
+
+	return content, fetchedAt, nil
+}
+
+func (c helmCache) write(u string, content []byte) error {
+	digest := sha256Digest(content)
+
+	if err := os.MkdirAll(filepath.Dir(c.blobPath(digest)), 0700); err != nil {
+		return errors.Wrap(err, "failed to create blobs dir")
+	}
+	if err := writeFileAtomic(c.blobPath(digest), content); err != nil {
+		return errors.Wrap(err, "failed to write blob")
+	}
+
+	ref := helmCacheRef{
+		URL:       u,
+		Digest:    digest,
+		FetchedAt: time.Now(),
+	}
+	b, err := json.Marshal(ref)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal ref")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.refPath(u)), 0700); err != nil {
+		return errors.Wrap(err, "failed to create refs dir")
+	}
+	if err := writeFileAtomic(c.refPath(u), b); err != nil {
+		return errors.Wrap(err, "failed to write ref")
+	}
+
+	return nil
+}
+
+func (c helmCache) readRef(u string) (*helmCacheRef, error) {
+	b, err := ioutil.ReadFile(c.refPath(u))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read ref")
+	}
+
+	ref := helmCacheRef{}
+	if err := json.Unmarshal(b, &ref); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal ref")
+	}
+
+	return &ref, nil
+}
+
+func (c helmCache) blobPath(digest string) string {
+	return filepath.Join(c.Dir, "blobs", "sha256", digest)
+}
+
+func (c helmCache) refPath(u string) string {
+	return filepath.Join(c.Dir, "refs", sha256Digest([]byte(u)))
+}
+
+func fetchHelmURL(u string, getters getter.Providers) ([]byte, error) {
+	parsedURL, err := url.Parse(u)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse url")
+	}
+
+	newGetter, err := getters.ByScheme(parsedURL.Scheme)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find getter")
+	}
+
+	g, err := newGetter(u, "", "", "")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create getter")
+	}
+
+	buf, err := g.Get(u)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get %s", u)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeFileAtomic writes to a temp file that is renamed, so that concurrent pulls
+// never read a partially written file
+func writeFileAtomic(filename string, content []byte) error {
+	tmpFile, err := ioutil.TempFile(filepath.Dir(filename), ".tmp")
+	if err != nil {
+		return errors.Wrap(err, "failed to create temp file")
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(content); err != nil {
+		tmpFile.Close()
+		return errors.Wrap(err, "failed to write temp file")
+	}
+	if err := tmpFile.Close(); err != nil {
+		return errors.Wrap(err, "failed to close temp file")
+	}
+
+	return os.Rename(tmpFile.Name(), filename)
+}
+
+func sha256Digest(content []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(content))
+}
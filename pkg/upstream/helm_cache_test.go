@@ -0,0 +1,105 @@
+package upstream
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_downloadHelmCache(t *testing.T) {
+	req := require.New(t)
+
+	chart := mustHelmChartArchive(t, map[string]string{
+		"cached/Chart.yaml":               "apiVersion: v1\nname: cached\nversion: 1.0.0\n",
+		"cached/templates/configmap.yaml": "apiVersion: v1\nkind: ConfigMap\n",
+	})
+
+	digest := sha256Digest(chart)
+	requests := map[string]int{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/index.yaml", func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		w.Write([]byte(`apiVersion: v1
+entries:
+  cached:
+  - name: cached
+    version: 1.0.0
+    digest: ` + digest + `
+    urls:
+    - cached-1.0.0.tgz
+  tampered:
+  - name: tampered
+    version: 1.0.0
+    digest: 0000000000000000000000000000000000000000000000000000000000000000
+    urls:
+    - cached-1.0.0.tgz
+`))
+	})
+	mux.HandleFunc("/cached-1.0.0.tgz", func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		w.Write(chart)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	cacheDir, err := ioutil.TempDir("", "kots")
+	req.NoError(err)
+	defer os.RemoveAll(cacheDir)
+
+	pull := func(chartURI string, cacheOptions HelmCacheOptions) (*Upstream, error) {
+		u, err := url.ParseRequestURI(chartURI)
+		req.NoError(err)
+		return downloadHelm(u, server.URL, HelmRepoOptions{}, cacheOptions)
+	}
+
+	// nothing is cached yet
+	_, err = pull("helm://cached/cached", HelmCacheOptions{Dir: cacheDir, Offline: true})
+	req.Error(err)
+
+	upstream, err := pull("helm://cached/cached", HelmCacheOptions{Dir: cacheDir, IndexTTL: time.Hour})
+	req.NoError(err)
+	assert.Equal(t, "1.0.0", upstream.UpdateCursor)
+	assert.Equal(t, map[string]int{"/index.yaml": 1, "/cached-1.0.0.tgz": 1}, requests)
+
+	_, err = os.Stat(filepath.Join(cacheDir, "blobs", "sha256", digest))
+	req.NoError(err)
+
+	// the index is fresh, and the chart is found by its digest
+	_, err = pull("helm://cached/cached", HelmCacheOptions{Dir: cacheDir, IndexTTL: time.Hour})
+	req.NoError(err)
+	assert.Equal(t, map[string]int{"/index.yaml": 1, "/cached-1.0.0.tgz": 1}, requests)
+
+	// the index has expired
+	_, err = pull("helm://cached/cached", HelmCacheOptions{Dir: cacheDir, IndexTTL: time.Nanosecond})
+	req.NoError(err)
+	assert.Equal(t, map[string]int{"/index.yaml": 2, "/cached-1.0.0.tgz": 1}, requests)
+
+	// offline uses the expired index
+	_, err = pull("helm://cached/cached", HelmCacheOptions{Dir: cacheDir, IndexTTL: time.Nanosecond, Offline: true})
+	req.NoError(err)
+	assert.Equal(t, map[string]int{"/index.yaml": 2, "/cached-1.0.0.tgz": 1}, requests)
+
+	// a corrupted blob is downloaded again
+	req.NoError(ioutil.WriteFile(filepath.Join(cacheDir, "blobs", "sha256", digest), []byte("corrupted"), 0600))
+	_, err = pull("helm://cached/cached", HelmCacheOptions{Dir: cacheDir, IndexTTL: time.Hour})
+	req.NoError(err)
+	assert.Equal(t, map[string]int{"/index.yaml": 2, "/cached-1.0.0.tgz": 2}, requests)
+
+	// the chart must match the digest in the index
+	_, err = pull("helm://cached/tampered", HelmCacheOptions{Dir: cacheDir, IndexTTL: time.Hour})
+	req.Error(err)
+	assert.Contains(t, err.Error(), "does not match")
+
+	// without a cache dir, nothing is cached
+	_, err = pull("helm://cached/cached", HelmCacheOptions{})
+	req.NoError(err)
+	assert.Equal(t, map[string]int{"/index.yaml": 3, "/cached-1.0.0.tgz": 4}, requests)
+}
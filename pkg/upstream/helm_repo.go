@@ -0,0 +1,163 @@
+package upstream
+
+import (
+	"bytes"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/pkg/errors"
+	"k8s.io/helm/pkg/getter"
+	"k8s.io/helm/pkg/helm/environment"
+	"k8s.io/helm/pkg/tlsutil"
+)
+
+// HelmRepoOptions are the credentials and tls settings used to connect to a
+// private helm repo
+type HelmRepoOptions struct {
+	Username              string
+	Password              string
+	CertFile              string
+	KeyFile               string
+	CAFile                string
+	InsecureSkipTLSVerify bool
+}
+
+func (o HelmRepoOptions) isEmpty() bool {
+	return o == HelmRepoOptions{}
+}
+
+// getters returns the getter providers to use when connecting to the repo at repoURI.
+// The credentials and certificates are only used for urls on the host of the repo, so
+// that charts and dependencies hosted elsewhere don't receive them. Urls of the other
+// repos in the repos file, such as the repos of dependencies, use the credentials that
+// are stored with those repos
+func (o HelmRepoOptions) getters(repoURI string, helmRepos *HelmRepos) getter.Providers {
+	if o.isEmpty() && !helmRepos.hasOptions() {
+		return getter.All(environment.EnvSettings{})
+	}
+
+	return getter.Providers{
+		{
+			Schemes: []string{"http", "https"},
+			New: func(URL, CertFile, KeyFile, CAFile string) (getter.Getter, error) {
+				if isSameHost(URL, repoURI) {
+					return newHelmHTTPGetter(o)
+				}
+				if helmRepo := helmRepos.getByURL(URL); helmRepo != nil {
+					return newHelmHTTPGetter(helmRepo.options())
+				}
+				return getter.NewHTTPGetter(URL, CertFile, KeyFile, CAFile)
+			},
+		},
+	}
+}
+
+// helmHTTPGetter is a getter that, unlike the default helm getter, supports skipping
+// tls verification
+type helmHTTPGetter struct {
+	client   *http.Client
+	username string
+	password string
+}
+
+func newHelmHTTPGetter(o HelmRepoOptions) (*helmHTTPGetter, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: o.InsecureSkipTLSVerify,
+	}
+	if o.CertFile != "" || o.KeyFile != "" {
+		cert, err := tlsutil.CertFromFilePair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load client certificate")
+		}
+		tlsConfig.Certificates = []tls.Certificate{*cert}
+	}
+	if o.CAFile != "" {
+		pool, err := tlsutil.CertPoolFromFile(o.CAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load ca bundle")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return &helmHTTPGetter{
+		client: &http.Client{
+			Transport: &http.Transport{
+				DisableCompression: true,
+				Proxy:              http.ProxyFromEnvironment,
+				TLSClientConfig:    tlsConfig,
+			},
+		},
+		username: o.Username,
+		password: o.Password,
+	}, nil
+}
+
+func (g *helmHTTPGetter) Get(href string) (*bytes.Buffer, error) {
+	req, err := http.NewRequest("GET", href, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create request")
+	}
+
+	if g.username != "" {
+		req.SetBasicAuth(g.username, g.password)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to execute request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed to fetch %s: %s", href, resp.Status)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if _, err := io.Copy(buf, resp.Body); err != nil {
+		return nil, errors.Wrap(err, "failed to read response body")
+	}
+
+	return buf, nil
+}
+
+// parseHelmRepoURI removes any credentials from the repo uri, so that they are
+// not written to disk with the uri, and adds them to the options when they are not
+// already set there
+func parseHelmRepoURI(repoURI string, o HelmRepoOptions) (string, HelmRepoOptions, error) {
+	if repoURI == "" {
+		return repoURI, o, nil
+	}
+
+	u, err := url.Parse(repoURI)
+	if err != nil {
+		return "", o, errors.Wrap(err, "failed to parse repo uri")
+	}
+	if u.User == nil {
+		return repoURI, o, nil
+	}
+
+	if o.Username == "" {
+		o.Username = u.User.Username()
+		if password, ok := u.User.Password(); ok && o.Password == "" {
+			o.Password = password
+		}
+	}
+	u.User = nil
+
+	return u.String(), o, nil
+}
+
+func isSameHost(a string, b string) bool {
+	aURL, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	bURL, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+
+	return aURL.Host == bURL.Host
+}
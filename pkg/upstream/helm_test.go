@@ -1,11 +1,15 @@
 package upstream
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/repo"
 )
 
 func Test_parseHelmURL(t *testing.T) {
@@ -30,6 +34,20 @@ func Test_parseHelmURL(t *testing.T) {
 			expectedChartName:    "mysql",
 			expectedChartVersion: "1.3.1",
 		},
+		{
+			name:                 "stable/mysql@~1.3",
+			uri:                  "helm://stable/mysql@~1.3",
+			expectedRepo:         "stable",
+			expectedChartName:    "mysql",
+			expectedChartVersion: "~1.3",
+		},
+		{
+			name:                 "stable/mysql@>=2.0 <3.0",
+			uri:                  "helm://stable/mysql@>=2.0 <3.0",
+			expectedRepo:         "stable",
+			expectedChartName:    "mysql",
+			expectedChartVersion: ">=2.0 <3.0",
+		},
 	}
 
 	for _, test := range tests {
@@ -47,3 +65,157 @@ func Test_parseHelmURL(t *testing.T) {
 		})
 	}
 }
+
+func Test_parseHelmURLInvalidVersion(t *testing.T) {
+	u, err := url.ParseRequestURI("helm://stable/mysql@not-a-version")
+	require.NoError(t, err)
+
+	_, _, _, err = parseHelmURL(u)
+	require.Error(t, err)
+}
+
+func Test_resolveHelmChartVersion(t *testing.T) {
+	chartVersions := []*repo.ChartVersion{}
+	for _, version := range []string{"1.3.0", "1.4.0", "1.4.2", "1.5.0-beta.1", "2.0.0", "2.1.0", "3.0.0-rc.1", "3.0.0"} {
+		chartVersions = append(chartVersions, &repo.ChartVersion{
+			Metadata: &chart.Metadata{Name: "mysql", Version: version},
+		})
+	}
+
+	tests := []struct {
+		constraint  string
+		expected    string
+		expectError bool
+	}{
+		{
+			constraint: "",
+			expected:   "3.0.0",
+		},
+		{
+			constraint: "1.4.0",
+			expected:   "1.4.0",
+		},
+		{
+			constraint: "~1.4",
+			expected:   "1.4.2",
+		},
+		{
+			constraint: ">=2.0 <3.0",
+			expected:   "2.1.0",
+		},
+		{
+			constraint: "^1.0",
+			expected:   "1.4.2",
+		},
+		{
+			constraint: "~1.5.0-0",
+			expected:   "1.5.0-beta.1",
+		},
+		{
+			constraint: "3.0.0-rc.1",
+			expected:   "3.0.0-rc.1",
+		},
+		{
+			constraint:  ">=4.0",
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.constraint, func(t *testing.T) {
+			req := require.New(t)
+
+			version, err := resolveHelmChartVersion(chartVersions, test.constraint)
+			if test.expectError {
+				req.Error(err)
+				return
+			}
+			req.NoError(err)
+			assert.Equal(t, test.expected, version)
+		})
+	}
+}
+
+func Test_fetchHelmDependencies(t *testing.T) {
+	req := require.New(t)
+
+	index := `apiVersion: v1
+entries:
+  redis:
+  - name: redis
+    version: 1.1.0
+    urls:
+    - redis-1.1.0.tgz
+  - name: redis
+    version: 1.0.0
+    urls:
+    - redis-1.0.0.tgz
+  broken:
+  - name: broken
+    version: 1.0.0
+`
+	mux := http.NewServeMux()
+	mux.HandleFunc("/index.yaml", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(index))
+	})
+	mux.HandleFunc("/redis-1.1.0.tgz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("redis-1.1.0"))
+	})
+	mux.HandleFunc("/redis-1.0.0.tgz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("redis-1.0.0"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	requirements := UpstreamFile{
+		Path: "requirements.yaml",
+		Content: []byte(`dependencies:
+- name: redis
+  version: ^1.0.0
+  repository: ` + server.URL + `
+- name: memcached
+  version: 2.0.0
+  repository: ` + server.URL + `
+`),
+	}
+	vendored := UpstreamFile{
+		Path:    "charts/memcached-2.0.0.tgz",
+		Content: []byte("memcached-2.0.0"),
+	}
+
+	files, err := fetchHelmDependencies([]UpstreamFile{requirements, vendored}, HelmRepoOptions{}.getters("", nil), helmCache{})
+	req.NoError(err)
+	assert.Equal(t, []UpstreamFile{
+		requirements,
+		vendored,
+		{Path: "charts/redis-1.1.0.tgz", Content: []byte("redis-1.1.0")},
+	}, files)
+
+	lock := UpstreamFile{
+		Path:    "requirements.lock",
+		Content: []byte("dependencies:\n- name: redis\n  version: 1.0.0\n  repository: " + server.URL + "\n"),
+	}
+	files, err = fetchHelmDependencies([]UpstreamFile{requirements, lock, vendored}, HelmRepoOptions{}.getters("", nil), helmCache{})
+	req.NoError(err)
+	assert.Contains(t, files, UpstreamFile{Path: "charts/redis-1.0.0.tgz", Content: []byte("redis-1.0.0")})
+
+	// an index entry without urls is an error
+	broken := UpstreamFile{
+		Path:    "requirements.yaml",
+		Content: []byte("dependencies:\n- name: broken\n  version: 1.0.0\n  repository: " + server.URL + "\n"),
+	}
+	_, err = fetchHelmDependencies([]UpstreamFile{broken}, HelmRepoOptions{}.getters("", nil), helmCache{})
+	req.Error(err)
+	assert.Contains(t, err.Error(), "chart broken version 1.0.0 has no urls")
+}
+
+func Test_isHelmDependencyVendored(t *testing.T) {
+	files := []UpstreamFile{
+		{Path: "charts/redis-ha-1.0.0.tgz"},
+		{Path: "charts/postgresql/Chart.yaml"},
+	}
+
+	assert.False(t, isHelmDependencyVendored(files, "redis"))
+	assert.True(t, isHelmDependencyVendored(files, "redis-ha"))
+	assert.True(t, isHelmDependencyVendored(files, "postgresql"))
+}
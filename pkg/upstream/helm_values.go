@@ -0,0 +1,99 @@
+package upstream
+
+import (
+	"io/ioutil"
+	"path"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	"k8s.io/helm/pkg/strvals"
+)
+
+// addHelmValues merges the values files, in order, with the --set values on top and
+// stores the result in the userdata so that the same values are used for every render
+func addHelmValues(u *Upstream, fetchOptions *FetchOptions) error {
+	if len(fetchOptions.HelmValuesFiles) == 0 && len(fetchOptions.HelmOptions) == 0 {
+		return nil
+	}
+
+	values := map[string]interface{}{}
+	for _, valuesFile := range fetchOptions.HelmValuesFiles {
+		content, err := ioutil.ReadFile(valuesFile)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read values file %s", valuesFile)
+		}
+
+		fileValues := map[string]interface{}{}
+		if err := yaml.Unmarshal(content, &fileValues); err != nil {
+			return errors.Wrapf(err, "failed to unmarshal values file %s", valuesFile)
+		}
+
+		values = mergeHelmValueMaps(values, fileValues)
+	}
+
+	for _, value := range fetchOptions.HelmOptions {
+		if err := strvals.ParseInto(value, values); err != nil {
+			return errors.Wrap(err, "failed to parse helm value")
+		}
+	}
+
+	content, err := yaml.Marshal(values)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal helm values")
+	}
+
+	u.Files = append(u.Files, UpstreamFile{
+		Path:    path.Join("userdata", "values.yaml"),
+		Content: content,
+	})
+
+	return nil
+}
+
+// mergeHelmValues merges the values from a previous pull with the values from this pull.
+// Values in the current pull take precedence, and if there are none, the previous values
+// are kept as is
+func mergeHelmValues(previousValues []byte, currentValues []byte) ([]byte, error) {
+	if currentValues == nil {
+		return previousValues, nil
+	}
+
+	prev := map[string]interface{}{}
+	if err := yaml.Unmarshal(previousValues, &prev); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal previous values")
+	}
+
+	current := map[string]interface{}{}
+	if err := yaml.Unmarshal(currentValues, &current); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal current values")
+	}
+
+	b, err := yaml.Marshal(mergeHelmValueMaps(prev, current))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal merged values")
+	}
+
+	return b, nil
+}
+
+// mergeHelmValueMaps deep merges override into base. Nested maps are merged, and any
+// other value in override replaces the value in base
+func mergeHelmValueMaps(base map[string]interface{}, override map[string]interface{}) map[string]interface{} {
+	merged := map[string]interface{}{}
+	for k, v := range base {
+		merged[k] = v
+	}
+
+	for k, v := range override {
+		overrideMap, isOverrideMap := v.(map[string]interface{})
+		baseMap, isBaseMap := merged[k].(map[string]interface{})
+		if isOverrideMap && isBaseMap {
+			merged[k] = mergeHelmValueMaps(baseMap, overrideMap)
+			continue
+		}
+
+		merged[k] = v
+	}
+
+	return merged
+}
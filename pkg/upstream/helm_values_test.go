@@ -0,0 +1,67 @@
+package upstream
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_addHelmValues(t *testing.T) {
+	req := require.New(t)
+
+	tmpDir, err := ioutil.TempDir("", "kots")
+	req.NoError(err)
+	defer os.RemoveAll(tmpDir)
+
+	first := filepath.Join(tmpDir, "first.yaml")
+	req.NoError(ioutil.WriteFile(first, []byte("image:\n  repository: nginx\n  tag: \"1.0\"\nreplicas: 1\n"), 0644))
+	second := filepath.Join(tmpDir, "second.yaml")
+	req.NoError(ioutil.WriteFile(second, []byte("image:\n  tag: \"2.0\"\nreplicas: 2\n"), 0644))
+
+	u := &Upstream{Type: "helm"}
+	err = addHelmValues(u, &FetchOptions{
+		HelmValuesFiles: []string{first, second},
+		HelmOptions:     []string{"replicas=3"},
+	})
+	req.NoError(err)
+
+	req.Len(u.Files, 1)
+	assert.Equal(t, "userdata/values.yaml", u.Files[0].Path)
+	assert.Equal(t, "image:\n  repository: nginx\n  tag: \"2.0\"\nreplicas: 3\n", string(u.Files[0].Content))
+}
+
+func Test_mergeHelmValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous string
+		current  []byte
+		expected string
+	}{
+		{
+			name:     "no current values",
+			previous: "replicas: 2\n",
+			current:  nil,
+			expected: "replicas: 2\n",
+		},
+		{
+			name:     "current values override",
+			previous: "image:\n  tag: \"1.0\"\nreplicas: 2\n",
+			current:  []byte("image:\n  repository: nginx\nreplicas: 3\n"),
+			expected: "image:\n  repository: nginx\n  tag: \"1.0\"\nreplicas: 3\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := require.New(t)
+
+			merged, err := mergeHelmValues([]byte(test.previous), test.current)
+			req.NoError(err)
+			assert.Equal(t, test.expected, string(merged))
+		})
+	}
+}
@@ -1,9 +1,184 @@
 package upstream
 
 import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
 	"github.com/pkg/errors"
 )
 
+const (
+	httpContentYAML  = "yaml"
+	httpContentTarGz = "tgz"
+	httpContentTar   = "tar"
+	httpContentZip   = "zip"
+)
+
 func downloadHttp(httpURI string) (*Upstream, error) {
-	return nil, errors.New("not implemented")
+	u, err := url.ParseRequestURI(httpURI)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse uri")
+	}
+
+	resp, err := http.Get(httpURI)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to execute get request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, errors.Errorf("unexpected result from get request: %d", resp.StatusCode)
+	}
+
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read response body")
+	}
+
+	var files []UpstreamFile
+	switch getHttpContentType(resp.Header.Get("Content-Type"), u.Path) {
+	case httpContentTarGz:
+		tmpFile, err := ioutil.TempFile("", "kots")
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create temp file")
+		}
+		defer os.Remove(tmpFile.Name())
+
+		if _, err := tmpFile.Write(content); err != nil {
+			tmpFile.Close()
+			return nil, errors.Wrap(err, "failed to write temp file")
+		}
+		tmpFile.Close()
+
+		files, err = readTarGz(tmpFile.Name())
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read tar gz")
+		}
+	case httpContentTar:
+		files, err = readTarReader(bytes.NewReader(content))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read tar")
+		}
+	case httpContentZip:
+		files, err = readZip(content)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read zip")
+		}
+	default:
+		filename := path.Base(u.Path)
+		if ext := path.Ext(filename); ext != ".yaml" && ext != ".yml" {
+			filename = "upstream.yaml"
+		}
+		files = []UpstreamFile{
+			{
+				Path:    filename,
+				Content: content,
+			},
+		}
+	}
+
+	// prefer the headers that the server uses for caching, but fall back to
+	// the content itself so that a change is always detectable
+	updateCursor := resp.Header.Get("ETag")
+	if updateCursor == "" {
+		updateCursor = resp.Header.Get("Last-Modified")
+	}
+	if updateCursor == "" {
+		updateCursor = fmt.Sprintf("%x", sha256.Sum256(content))
+	}
+
+	upstream := &Upstream{
+		URI:          httpURI,
+		Name:         getHttpUpstreamName(u),
+		Type:         "http",
+		Files:        files,
+		UpdateCursor: updateCursor,
+	}
+
+	return upstream, nil
+}
+
+// getHttpContentType returns the kind of content that was downloaded. The content
+// type header is used when it's specific, otherwise the extension from the url decides
+func getHttpContentType(contentType string, urlPath string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/gzip", "application/x-gzip", "application/x-compressed-tar":
+		return httpContentTarGz
+	case "application/x-tar":
+		return httpContentTar
+	case "application/zip", "application/x-zip-compressed":
+		return httpContentZip
+	}
+
+	switch {
+	case strings.HasSuffix(urlPath, ".tar.gz"), strings.HasSuffix(urlPath, ".tgz"):
+		return httpContentTarGz
+	case strings.HasSuffix(urlPath, ".tar"):
+		return httpContentTar
+	case strings.HasSuffix(urlPath, ".zip"):
+		return httpContentZip
+	}
+
+	return httpContentYAML
+}
+
+func getHttpUpstreamName(u *url.URL) string {
+	name := path.Base(u.Path)
+	for _, ext := range []string{".tar.gz", ".tgz", ".tar", ".zip", ".yaml", ".yml"} {
+		name = strings.TrimSuffix(name, ext)
+	}
+
+	if name == "" || name == "." || name == "/" {
+		return u.Hostname()
+	}
+
+	return name
+}
+
+func readZip(content []byte) ([]UpstreamFile, error) {
+	zipReader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create zip reader")
+	}
+
+	upstreamFiles := []UpstreamFile{}
+	for _, f := range zipReader.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		name, err := cleanArchivePath(f.Name)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid file in zip archive")
+		}
+
+		r, err := f.Open()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to open file in zip archive")
+		}
+		fileContent, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read file from zip archive")
+		}
+
+		upstreamFile := UpstreamFile{
+			Path:    name,
+			Content: fileContent,
+		}
+
+		upstreamFiles = append(upstreamFiles, upstreamFile)
+	}
+
+	return removeCommonPrefix(upstreamFiles), nil
 }
@@ -0,0 +1,185 @@
+package upstream
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_getHttpContentType(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		urlPath     string
+		expected    string
+	}{
+		{
+			name:        "yaml by extension",
+			contentType: "text/plain; charset=utf-8",
+			urlPath:     "/app.yaml",
+			expected:    httpContentYAML,
+		},
+		{
+			name:        "tgz by extension",
+			contentType: "application/octet-stream",
+			urlPath:     "/app.tar.gz",
+			expected:    httpContentTarGz,
+		},
+		{
+			name:        "tgz by content type",
+			contentType: "application/gzip",
+			urlPath:     "/download",
+			expected:    httpContentTarGz,
+		},
+		{
+			name:        "tar by content type",
+			contentType: "application/x-tar",
+			urlPath:     "/download",
+			expected:    httpContentTar,
+		},
+		{
+			name:        "tar by extension",
+			contentType: "application/octet-stream",
+			urlPath:     "/app.tar",
+			expected:    httpContentTar,
+		},
+		{
+			name:        "zip by content type",
+			contentType: "application/zip",
+			urlPath:     "/download",
+			expected:    httpContentZip,
+		},
+		{
+			name:        "zip by extension",
+			contentType: "",
+			urlPath:     "/app.zip",
+			expected:    httpContentZip,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, getHttpContentType(test.contentType, test.urlPath))
+		})
+	}
+}
+
+func Test_downloadHttp(t *testing.T) {
+	req := require.New(t)
+
+	var tarGz bytes.Buffer
+	gzw := gzip.NewWriter(&tarGz)
+	tw := tar.NewWriter(gzw)
+	for name, content := range map[string]string{"app/deployment.yaml": "kind: Deployment", "app/service.yaml": "kind: Service"} {
+		req.NoError(tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		req.NoError(err)
+	}
+	req.NoError(tw.Close())
+	req.NoError(gzw.Close())
+
+	var plainTar bytes.Buffer
+	tw = tar.NewWriter(&plainTar)
+	req.NoError(tw.WriteHeader(&tar.Header{Name: "app/deployment.yaml", Mode: 0644, Size: 16, Typeflag: tar.TypeReg}))
+	_, err := tw.Write([]byte("kind: Deployment"))
+	req.NoError(err)
+	req.NoError(tw.Close())
+
+	var zipped bytes.Buffer
+	zw := zip.NewWriter(&zipped)
+	w, err := zw.Create("app/deployment.yaml")
+	req.NoError(err)
+	_, err = w.Write([]byte("kind: Deployment"))
+	req.NoError(err)
+	req.NoError(zw.Close())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/app.yaml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"abc"`)
+		w.Write([]byte("kind: Deployment\n---\nkind: Service"))
+	})
+	mux.HandleFunc("/app.tar.gz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Last-Modified", "Wed, 21 Oct 2015 07:28:00 GMT")
+		w.Write(tarGz.Bytes())
+	})
+	mux.HandleFunc("/app.tar", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-tar")
+		w.Write(plainTar.Bytes())
+	})
+	mux.HandleFunc("/download", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/zip")
+		w.Write(zipped.Bytes())
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	u, err := downloadHttp(server.URL + "/app.yaml")
+	req.NoError(err)
+	assert.Equal(t, "http", u.Type)
+	assert.Equal(t, "app", u.Name)
+	assert.Equal(t, `"abc"`, u.UpdateCursor)
+	assert.Equal(t, []UpstreamFile{{Path: "app.yaml", Content: []byte("kind: Deployment\n---\nkind: Service")}}, u.Files)
+
+	u, err = downloadHttp(server.URL + "/app.tar.gz")
+	req.NoError(err)
+	assert.Equal(t, "app", u.Name)
+	assert.Equal(t, "Wed, 21 Oct 2015 07:28:00 GMT", u.UpdateCursor)
+	assert.ElementsMatch(t, []UpstreamFile{
+		{Path: "deployment.yaml", Content: []byte("kind: Deployment")},
+		{Path: "service.yaml", Content: []byte("kind: Service")},
+	}, u.Files)
+
+	u, err = downloadHttp(server.URL + "/app.tar")
+	req.NoError(err)
+	assert.Equal(t, "app", u.Name)
+	assert.Equal(t, []UpstreamFile{{Path: "deployment.yaml", Content: []byte("kind: Deployment")}}, u.Files)
+
+	u, err = downloadHttp(server.URL + "/download")
+	req.NoError(err)
+	assert.Equal(t, "download", u.Name)
+	assert.NotEmpty(t, u.UpdateCursor)
+	assert.Equal(t, []UpstreamFile{{Path: "deployment.yaml", Content: []byte("kind: Deployment")}}, u.Files)
+
+	_, err = downloadHttp(server.URL + "/missing.yaml")
+	req.Error(err)
+}
+
+func Test_readArchiveOutsideOfRoot(t *testing.T) {
+	names := []string{"../evil.yaml", "app/../../evil.yaml", "/etc/evil.yaml"}
+
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			req := require.New(t)
+
+			var zipped bytes.Buffer
+			zw := zip.NewWriter(&zipped)
+			w, err := zw.Create(name)
+			req.NoError(err)
+			_, err = w.Write([]byte("kind: Deployment"))
+			req.NoError(err)
+			req.NoError(zw.Close())
+
+			_, err = readZip(zipped.Bytes())
+			req.Error(err)
+			assert.Contains(t, err.Error(), "is outside of the archive")
+
+			var tarred bytes.Buffer
+			tw := tar.NewWriter(&tarred)
+			req.NoError(tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: 16, Typeflag: tar.TypeReg}))
+			_, err = tw.Write([]byte("kind: Deployment"))
+			req.NoError(err)
+			req.NoError(tw.Close())
+
+			_, err = readTarReader(&tarred)
+			req.Error(err)
+			assert.Contains(t, err.Error(), "is outside of the archive")
+		})
+	}
+}
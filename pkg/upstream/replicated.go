@@ -84,11 +84,18 @@ func downloadReplicated(u *url.URL, localPath string, license *kotsv1beta1.Licen
 		release = downloadedRelease
 	}
 
+	return releaseToUpstream(u.RequestURI(), release, license)
+}
+
+// releaseToUpstream creates the default config values and adds the license, if one
+// was provided, before converting the release to an upstream
+func releaseToUpstream(uri string, release *Release, license *kotsv1beta1.License) (*Upstream, error) {
 	// Find the config in the upstream and write out default values
 	application := findAppInRelease(release)
 	config := findConfigInRelease(release)
+	generatedValues := &kotsv1beta1.GeneratedValues{}
 	if config != nil {
-		configValues, err := createEmptyConfigValues(application.Name, config)
+		configValues, err := createEmptyConfigValues(application.Name, config, license, generatedValues)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to create empty config values")
 		}
@@ -107,12 +114,13 @@ func downloadReplicated(u *url.URL, localPath string, license *kotsv1beta1.Licen
 	}
 
 	upstream := &Upstream{
-		URI:          u.RequestURI(),
-		Name:         application.Name,
-		Files:        files,
-		Type:         "replicated",
-		UpdateCursor: release.UpdateCursor,
-		VersionLabel: release.VersionLabel,
+		URI:             uri,
+		Name:            application.Name,
+		Files:           files,
+		Type:            "replicated",
+		UpdateCursor:    release.UpdateCursor,
+		VersionLabel:    release.VersionLabel,
+		GeneratedValues: generatedValues,
 	}
 
 	return upstream, nil
@@ -265,12 +273,15 @@ func downloadReplicatedApp(replicatedUpstream *ReplicatedUpstream, license *kots
 			return nil, errors.Wrap(err, "failed to get next file from reader")
 		}
 
-		name := header.Name
-
 		switch header.Typeflag {
 		case tar.TypeDir:
 			continue
 		case tar.TypeReg:
+			name, err := cleanArchivePath(header.Name)
+			if err != nil {
+				return nil, errors.Wrap(err, "invalid file in tar")
+			}
+
 			content, err := ioutil.ReadAll(tarReader)
 			if err != nil {
 				return nil, errors.Wrap(err, "failed to read file from tar")
@@ -311,18 +322,54 @@ func mustMarshalConfigValues(configValues *kotsv1beta1.ConfigValues) []byte {
 	return b.Bytes()
 }
 
-func createEmptyConfigValues(applicationName string, config *kotsv1beta1.Config) (*kotsv1beta1.ConfigValues, error) {
+// createEmptyConfigValues renders the values of the config items. The random strings that
+// the values generate are kept in the generated values, so that the config values and the
+// installation have the same strings
+func createEmptyConfigValues(applicationName string, config *kotsv1beta1.Config, license *kotsv1beta1.License, generatedValues *kotsv1beta1.GeneratedValues) (*kotsv1beta1.ConfigValues, error) {
 	emptyValues := kotsv1beta1.ConfigValuesSpec{
 		Values: map[string]string{},
 	}
 
 	builder := template.Builder{}
-	builder.AddCtx(template.StaticCtx{})
+	builder.AddCtx(template.StaticCtx{GeneratedValues: generatedValues})
+	// there's no cluster when the upstream is pulled, but the defaults of the config items
+	// can still use the license and kubernetes functions
+	builder.AddCtx(template.LicenseCtx{License: license})
+	builder.AddCtx(&template.KubernetesCtx{})
+
+	// items that are not enabled by their when condition don't get a value
+	configCtx, err := builder.NewConfigContext(config.Spec.Groups, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create config context")
+	}
+	// values can be derived from other items
+	builder.AddCtx(configCtx)
 
 	for _, group := range config.Spec.Groups {
 		for _, item := range group.Items {
+			if _, ok := configCtx.ItemValues[item.Name]; !ok {
+				continue
+			}
+
+			if group.Repeatable || item.Multiple {
+				if len(item.MultiValue) == 0 {
+					continue
+				}
+
+				multiValue, err := builder.ConfigItemMultiValue(item)
+				if err != nil {
+					return nil, errors.Wrap(err, "failed to render config item multi value")
+				}
+
+				if emptyValues.MultiValues == nil {
+					emptyValues.MultiValues = map[string][]string{}
+				}
+				emptyValues.MultiValues[item.Name] = multiValue
+				continue
+			}
+
 			if item.Value != "" {
-				rendered, err := builder.RenderTemplate(item.Name, item.Value)
+				rendered, err := builder.ConfigItemValue(item)
 				if err != nil {
 					return nil, errors.Wrap(err, "failed to render config item value")
 				}
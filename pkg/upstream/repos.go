@@ -1,5 +1,18 @@
 package upstream
 
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/util"
+)
+
 var KnownRepos = map[string]string{
 	"stable":  "https://kubernetes-charts.storage.googleapis.com",
 	"local":   "http://127.0.0.1:8879",
@@ -7,3 +20,178 @@ var KnownRepos = map[string]string{
 	"gomods":  "https://athens.blob.core.windows.net/charts",
 	"harbor":  "https://helm.goharbor.io",
 }
+
+// HelmRepo is a named helm repo added with "kots repo add"
+type HelmRepo struct {
+	Name                  string `json:"name"`
+	URI                   string `json:"uri"`
+	Username              string `json:"username,omitempty"`
+	Password              string `json:"password,omitempty"`
+	CertFile              string `json:"certFile,omitempty"`
+	KeyFile               string `json:"keyFile,omitempty"`
+	CAFile                string `json:"caFile,omitempty"`
+	InsecureSkipTLSVerify bool   `json:"insecureSkipTLSVerify,omitempty"`
+}
+
+// HelmRepos is the list of repos stored in the repos file
+type HelmRepos struct {
+	Repos []HelmRepo `json:"repos"`
+}
+
+// HelmReposFilename returns the path of the repos file in the home directory
+func HelmReposFilename() (string, error) {
+	home := util.HomeDir()
+	if home == "" {
+		return "", errors.New("failed to get home directory, HOME is not set")
+	}
+
+	return filepath.Join(home, ".kots", "repos.yaml"), nil
+}
+
+// LoadHelmRepos reads the repos file, returning an empty list when the file does
+// not exist yet
+func LoadHelmRepos(filename string) (*HelmRepos, error) {
+	content, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return &HelmRepos{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read repos file")
+	}
+
+	helmRepos := HelmRepos{}
+	if err := yaml.Unmarshal(content, &helmRepos); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal repos file")
+	}
+
+	return &helmRepos, nil
+}
+
+// Save writes the repos file. The file can contain credentials, so it's only
+// readable by the current user
+func (r *HelmRepos) Save(filename string) error {
+	sort.Slice(r.Repos, func(i, j int) bool {
+		return r.Repos[i].Name < r.Repos[j].Name
+	})
+
+	b, err := yaml.Marshal(r)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal repos")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return errors.Wrap(err, "failed to create repos file directory")
+	}
+
+	if err := ioutil.WriteFile(filename, b, 0600); err != nil {
+		return errors.Wrap(err, "failed to write repos file")
+	}
+
+	return nil
+}
+
+// Get returns the repo with the name, or nil if there is no such repo
+func (r *HelmRepos) Get(name string) *HelmRepo {
+	for i, helmRepo := range r.Repos {
+		if helmRepo.Name == name {
+			return &r.Repos[i]
+		}
+	}
+
+	return nil
+}
+
+// Add adds a repo, replacing an existing repo with the same name only when
+// overwrite is set
+func (r *HelmRepos) Add(helmRepo HelmRepo, overwrite bool) error {
+	if helmRepo.Name == "" || strings.ContainsAny(helmRepo.Name, "/@:") {
+		return errors.Errorf("invalid repo name %q", helmRepo.Name)
+	}
+	if !strings.HasPrefix(helmRepo.URI, "http://") && !strings.HasPrefix(helmRepo.URI, "https://") {
+		return errors.Errorf("repo uri %q must be http or https", helmRepo.URI)
+	}
+
+	if existing := r.Get(helmRepo.Name); existing != nil {
+		if !overwrite {
+			return errors.Errorf("repo %q already exists", helmRepo.Name)
+		}
+		*existing = helmRepo
+		return nil
+	}
+
+	r.Repos = append(r.Repos, helmRepo)
+	return nil
+}
+
+// Remove removes the repo with the name
+func (r *HelmRepos) Remove(name string) error {
+	for i, helmRepo := range r.Repos {
+		if helmRepo.Name == name {
+			r.Repos = append(r.Repos[:i], r.Repos[i+1:]...)
+			return nil
+		}
+	}
+
+	return errors.Errorf("repo %q not found", name)
+}
+
+// getByURL returns the repo that the url is in, or nil if the url is not in any of the
+// repos. When the uris of several repos match, the longest one is used
+func (r *HelmRepos) getByURL(u string) *HelmRepo {
+	if r == nil {
+		return nil
+	}
+
+	var found *HelmRepo
+	for i, helmRepo := range r.Repos {
+		if !isSameHost(u, helmRepo.URI) || !isInRepo(u, helmRepo.URI) {
+			continue
+		}
+		if found == nil || len(helmRepo.URI) > len(found.URI) {
+			found = &r.Repos[i]
+		}
+	}
+
+	return found
+}
+
+// hasOptions is true when any of the repos has credentials or tls settings
+func (r *HelmRepos) hasOptions() bool {
+	if r == nil {
+		return false
+	}
+
+	for _, helmRepo := range r.Repos {
+		if !helmRepo.options().isEmpty() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isInRepo is true when the path of the url is in the path of the repo uri
+func isInRepo(u string, repoURI string) bool {
+	parsedURL, err := url.Parse(u)
+	if err != nil {
+		return false
+	}
+	parsedRepoURI, err := url.Parse(repoURI)
+	if err != nil {
+		return false
+	}
+
+	repoPath := strings.TrimSuffix(parsedRepoURI.Path, "/")
+	return parsedURL.Path == repoPath || strings.HasPrefix(parsedURL.Path, repoPath+"/")
+}
+
+func (h HelmRepo) options() HelmRepoOptions {
+	return HelmRepoOptions{
+		Username:              h.Username,
+		Password:              h.Password,
+		CertFile:              h.CertFile,
+		KeyFile:               h.KeyFile,
+		CAFile:                h.CAFile,
+		InsecureSkipTLSVerify: h.InsecureSkipTLSVerify,
+	}
+}
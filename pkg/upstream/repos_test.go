@@ -0,0 +1,166 @@
+package upstream
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_HelmRepos(t *testing.T) {
+	req := require.New(t)
+
+	tmpDir, err := ioutil.TempDir("", "kots")
+	req.NoError(err)
+	defer os.RemoveAll(tmpDir)
+
+	filename := filepath.Join(tmpDir, ".kots", "repos.yaml")
+
+	helmRepos, err := LoadHelmRepos(filename)
+	req.NoError(err)
+	assert.Empty(t, helmRepos.Repos)
+
+	req.NoError(helmRepos.Add(HelmRepo{Name: "private", URI: "https://charts.example.com", Username: "user", Password: "pass"}, false))
+	req.NoError(helmRepos.Add(HelmRepo{Name: "internal", URI: "http://charts.internal"}, false))
+	req.Error(helmRepos.Add(HelmRepo{Name: "internal", URI: "http://other.internal"}, false))
+	req.NoError(helmRepos.Add(HelmRepo{Name: "internal", URI: "http://other.internal"}, true))
+	req.Error(helmRepos.Add(HelmRepo{Name: "bad/name", URI: "https://charts.example.com"}, false))
+	req.Error(helmRepos.Add(HelmRepo{Name: "oci", URI: "oci://charts.example.com"}, false))
+	req.NoError(helmRepos.Save(filename))
+
+	info, err := os.Stat(filename)
+	req.NoError(err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	loaded, err := LoadHelmRepos(filename)
+	req.NoError(err)
+	assert.Equal(t, []HelmRepo{
+		{Name: "internal", URI: "http://other.internal"},
+		{Name: "private", URI: "https://charts.example.com", Username: "user", Password: "pass"},
+	}, loaded.Repos)
+
+	req.NoError(loaded.Remove("internal"))
+	req.Error(loaded.Remove("internal"))
+	assert.Nil(t, loaded.Get("internal"))
+	assert.Equal(t, "https://charts.example.com", loaded.Get("private").URI)
+}
+
+func Test_getKnownHelmRepo(t *testing.T) {
+	req := require.New(t)
+
+	tmpDir, err := ioutil.TempDir("", "kots")
+	req.NoError(err)
+	defer os.RemoveAll(tmpDir)
+
+	home := os.Getenv("HOME")
+	defer os.Setenv("HOME", home)
+	os.Setenv("HOME", tmpDir)
+
+	filename, err := HelmReposFilename()
+	req.NoError(err)
+	helmRepos := HelmRepos{
+		Repos: []HelmRepo{
+			{Name: "ourrepo", URI: "https://charts.example.com"},
+			{Name: "stable", URI: "https://mirror.example.com/stable"},
+		},
+	}
+	req.NoError(helmRepos.Save(filename))
+
+	tests := []struct {
+		name        string
+		expectedURI string
+	}{
+		{
+			name:        "ourrepo",
+			expectedURI: "https://charts.example.com",
+		},
+		{
+			name:        "stable",
+			expectedURI: "https://mirror.example.com/stable",
+		},
+		{
+			name:        "elastic",
+			expectedURI: "https://helm.elastic.co",
+		},
+		{
+			name: "unknown",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := require.New(t)
+
+			helmRepo, err := getKnownHelmRepo(test.name)
+			req.NoError(err)
+			if test.expectedURI == "" {
+				assert.Nil(t, helmRepo)
+				return
+			}
+			req.NotNil(helmRepo)
+			assert.Equal(t, test.expectedURI, helmRepo.URI)
+		})
+	}
+}
+
+func Test_HelmReposGetByURL(t *testing.T) {
+	helmRepos := &HelmRepos{
+		Repos: []HelmRepo{
+			{Name: "charts", URI: "https://charts.example.com"},
+			{Name: "private", URI: "https://charts.example.com/private/"},
+			{Name: "other", URI: "https://other.example.com/charts"},
+		},
+	}
+
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{url: "https://charts.example.com/redis-1.0.0.tgz", expected: "charts"},
+		{url: "https://charts.example.com/private/redis-1.0.0.tgz", expected: "private"},
+		{url: "https://charts.example.com/privateer/redis-1.0.0.tgz", expected: "charts"},
+		{url: "https://other.example.com/charts/index.yaml", expected: "other"},
+		{url: "https://other.example.com/chartsmuseum/index.yaml"},
+		{url: "https://unknown.example.com/index.yaml"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.url, func(t *testing.T) {
+			helmRepo := helmRepos.getByURL(test.url)
+			if test.expected == "" {
+				assert.Nil(t, helmRepo)
+				return
+			}
+			require.NotNil(t, helmRepo)
+			assert.Equal(t, test.expected, helmRepo.Name)
+		})
+	}
+}
+
+func Test_HelmReposFilename(t *testing.T) {
+	req := require.New(t)
+
+	home := os.Getenv("HOME")
+	defer os.Setenv("HOME", home)
+	userProfile := os.Getenv("USERPROFILE")
+	defer os.Setenv("USERPROFILE", userProfile)
+
+	// the same home directory as the cli, so that kots repo and kots pull use one file
+	os.Setenv("HOME", "/home/kots")
+	os.Setenv("USERPROFILE", "/users/kots")
+	filename, err := HelmReposFilename()
+	req.NoError(err)
+	assert.Equal(t, filepath.Join("/home/kots", ".kots", "repos.yaml"), filename)
+
+	os.Unsetenv("HOME")
+	filename, err = HelmReposFilename()
+	req.NoError(err)
+	assert.Equal(t, filepath.Join("/users/kots", ".kots", "repos.yaml"), filename)
+
+	os.Unsetenv("USERPROFILE")
+	_, err = HelmReposFilename()
+	req.Error(err)
+}
@@ -1,5 +1,9 @@
 package upstream
 
+import (
+	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
+)
+
 type UpstreamFile struct {
 	Path    string
 	Content []byte
@@ -13,4 +17,8 @@ type Upstream struct {
 	UpdateCursor  string
 	VersionLabel  string
 	EncryptionKey string
+
+	// GeneratedValues are the certificates and keys that templates generated. They are
+	// kept in the installation and updated as the upstream is rendered
+	GeneratedValues *kotsv1beta1.GeneratedValues
 }
@@ -6,13 +6,18 @@ import (
 	"crypto/cipher"
 	"crypto/rand"
 	"encoding/base64"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
+	"reflect"
+	"strings"
 
 	"github.com/pkg/errors"
 	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
 	kotsscheme "github.com/replicatedhq/kots/kotskinds/client/kotsclientset/scheme"
+	kotsconfig "github.com/replicatedhq/kots/pkg/config"
+	"github.com/replicatedhq/kots/pkg/crypto"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/serializer/json"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -23,15 +28,13 @@ type WriteOptions struct {
 	CreateAppDir        bool
 	IncludeAdminConsole bool
 	SharedPassword      string
+	// RotateRandomStrings generates new random strings instead of keeping the ones from
+	// the previous pull, including the config values that were generated with them
+	RotateRandomStrings bool
 }
 
 func (u *Upstream) WriteUpstream(options WriteOptions) error {
-	renderDir := options.RootDir
-	if options.CreateAppDir {
-		renderDir = path.Join(renderDir, u.Name)
-	}
-
-	renderDir = path.Join(renderDir, "upstream")
+	renderDir := u.GetUpstreamDir(options)
 
 	if options.IncludeAdminConsole {
 		adminConsoleFiles, err := generateAdminConsoleFiles(renderDir, options.SharedPassword)
@@ -44,6 +47,7 @@ func (u *Upstream) WriteUpstream(options WriteOptions) error {
 
 	var previousValuesContent []byte
 	var previousInstallationContent []byte
+	var previousConfigValuesContent []byte
 	_, err := os.Stat(renderDir)
 	if err == nil {
 		// if there's already a values yaml, we need to save
@@ -67,11 +71,67 @@ func (u *Upstream) WriteUpstream(options WriteOptions) error {
 			previousInstallationContent = c
 		}
 
+		// and the config values, which are edited to configure the application
+		_, err = os.Stat(path.Join(renderDir, "userdata", "config.yaml"))
+		if err == nil {
+			c, err := ioutil.ReadFile(path.Join(renderDir, "userdata", "config.yaml"))
+			if err != nil {
+				return errors.Wrap(err, "failed to read existing config values")
+			}
+
+			previousConfigValuesContent = c
+		}
+
 		if err := os.RemoveAll(renderDir); err != nil {
 			return errors.Wrap(err, "failed to remove previous content in upstream")
 		}
 	}
 
+	// preserve the encryption key, if there already is one
+	encryptionKey, err := getEncryptionKey(previousInstallationContent)
+	if err != nil {
+		return errors.Wrap(err, "failed to get encryption key")
+	}
+	u.EncryptionKey = encryptionKey
+
+	// and the certificates and keys generated by templates, so they aren't rotated on update
+	generatedValues, err := getGeneratedValues(previousInstallationContent)
+	if err != nil {
+		return errors.Wrap(err, "failed to get generated values")
+	}
+	if options.RotateRandomStrings {
+		if previousConfigValuesContent != nil {
+			previousConfigValuesContent, err = removeRandomStringConfigValues(previousConfigValuesContent, generatedValues.RandomStrings, encryptionKey)
+			if err != nil {
+				return errors.Wrap(err, "failed to remove random string config values")
+			}
+		}
+		generatedValues.RandomStrings = nil
+	}
+	u.GeneratedValues = mergeGeneratedValues(generatedValues, u.GeneratedValues)
+
+	// the previous config values are kept, and only the items that are new in this
+	// version get their initial values. values that were edited are encrypted below
+	if previousConfigValuesContent != nil {
+		for i, f := range u.Files {
+			if f.Path == path.Join("userdata", "config.yaml") {
+				mergedConfigValues, err := mergeValues(previousConfigValuesContent, f.Content)
+				if err != nil {
+					return errors.Wrap(err, "failed to merge config values")
+				}
+
+				u.Files[i] = UpstreamFile{
+					Path:    f.Path,
+					Content: mergedConfigValues,
+				}
+			}
+		}
+	}
+
+	if err := encryptConfigValuesFile(u.Files, encryptionKey); err != nil {
+		return errors.Wrap(err, "failed to encrypt config values")
+	}
+
 	for _, file := range u.Files {
 		fileRenderPath := path.Join(renderDir, file.Path)
 		d, _ := path.Split(fileRenderPath)
@@ -86,7 +146,34 @@ func (u *Upstream) WriteUpstream(options WriteOptions) error {
 		}
 	}
 
-	if previousValuesContent != nil {
+	if previousValuesContent != nil && u.Type == "helm" {
+		var currentValuesContent []byte
+		for _, f := range u.Files {
+			if f.Path == path.Join("userdata", "values.yaml") {
+				currentValuesContent = f.Content
+			}
+		}
+
+		mergedValues, err := mergeHelmValues(previousValuesContent, currentValuesContent)
+		if err != nil {
+			return errors.Wrap(err, "failed to merge helm values")
+		}
+
+		if err := os.MkdirAll(path.Join(renderDir, "userdata"), 0755); err != nil {
+			return errors.Wrap(err, "failed to create userdata dir")
+		}
+		err = ioutil.WriteFile(path.Join(renderDir, "userdata", "values.yaml"), mergedValues, 0644)
+		if err != nil {
+			return errors.Wrap(err, "failed to replace values with previous values")
+		}
+
+		updatedValues := UpstreamFile{
+			Path:    path.Join("userdata", "values.yaml"),
+			Content: mergedValues,
+		}
+
+		u.Files = append(removeUpstreamFile(u.Files, updatedValues.Path), updatedValues)
+	} else if previousValuesContent != nil {
 		for i, f := range u.Files {
 			if f.Path == path.Join("userdata", "values.yaml") {
 				mergedValues, err := mergeValues(previousValuesContent, f.Content)
@@ -109,12 +196,18 @@ func (u *Upstream) WriteUpstream(options WriteOptions) error {
 		}
 	}
 
-	// Write the installation status (update cursor, etc)
-	// but preserving the encryption key, if there already is one
-	encryptionKey, err := getEncryptionKey(previousInstallationContent)
-	if err != nil {
-		return errors.Wrap(err, "failed to get encryption key")
+	if err := u.WriteInstallation(options); err != nil {
+		return errors.Wrap(err, "failed to write installation")
 	}
+
+	return nil
+}
+
+// WriteInstallation writes the installation status (update cursor, encryption key, generated
+// values, etc) to the upstream userdata
+func (u *Upstream) WriteInstallation(options WriteOptions) error {
+	renderDir := u.GetUpstreamDir(options)
+
 	installation := kotsv1beta1.Installation{
 		TypeMeta: metav1.TypeMeta{
 			APIVersion: "kots.io/v1beta1",
@@ -126,15 +219,22 @@ func (u *Upstream) WriteUpstream(options WriteOptions) error {
 		Spec: kotsv1beta1.InstallationSpec{
 			UpdateCursor:  u.UpdateCursor,
 			VersionLabel:  u.VersionLabel,
-			EncryptionKey: encryptionKey,
+			EncryptionKey: u.EncryptionKey,
 		},
 	}
+	if u.GeneratedValues != nil {
+		generatedValues, err := encryptGeneratedValues(u.GeneratedValues, u.EncryptionKey)
+		if err != nil {
+			return errors.Wrap(err, "failed to encrypt generated values")
+		}
+		installation.Spec.GeneratedValues = generatedValues
+	}
 	if _, err := os.Stat(path.Join(renderDir, "userdata")); os.IsNotExist(err) {
 		if err := os.MkdirAll(path.Join(renderDir, "userdata"), 0755); err != nil {
 			return errors.Wrap(err, "failed to create userdata dir")
 		}
 	}
-	err = ioutil.WriteFile(path.Join(renderDir, "userdata", "installation.yaml"), mustMarshalInstallation(&installation), 0644)
+	err := ioutil.WriteFile(path.Join(renderDir, "userdata", "installation.yaml"), mustMarshalInstallation(&installation), 0644)
 	if err != nil {
 		return errors.Wrap(err, "failed to write installation")
 	}
@@ -142,6 +242,26 @@ func (u *Upstream) WriteUpstream(options WriteOptions) error {
 	return nil
 }
 
+func removeUpstreamFile(files []UpstreamFile, filePath string) []UpstreamFile {
+	remaining := []UpstreamFile{}
+	for _, f := range files {
+		if f.Path != filePath {
+			remaining = append(remaining, f)
+		}
+	}
+
+	return remaining
+}
+
+func (u *Upstream) GetUpstreamDir(options WriteOptions) string {
+	renderDir := options.RootDir
+	if options.CreateAppDir {
+		renderDir = path.Join(renderDir, u.Name)
+	}
+
+	return path.Join(renderDir, "upstream")
+}
+
 func (u *Upstream) GetBaseDir(options WriteOptions) string {
 	renderDir := options.RootDir
 	if options.CreateAppDir {
@@ -151,6 +271,55 @@ func (u *Upstream) GetBaseDir(options WriteOptions) string {
 	return path.Join(renderDir, "base")
 }
 
+// encryptConfigValuesFile encrypts the values of password items in the config values,
+// so that they are not stored in plaintext
+func encryptConfigValuesFile(files []UpstreamFile, encryptionKey string) error {
+	kotsscheme.AddToScheme(scheme.Scheme)
+	decode := scheme.Codecs.UniversalDeserializer().Decode
+
+	var config *kotsv1beta1.Config
+	configValuesIndex := -1
+	for i, file := range files {
+		if file.Path == path.Join("userdata", "config.yaml") {
+			configValuesIndex = i
+			continue
+		}
+
+		obj, gvk, err := decode(file.Content, nil, nil)
+		if err != nil {
+			continue
+		}
+		if gvk.Group == "kots.io" && gvk.Version == "v1beta1" && gvk.Kind == "Config" {
+			config = obj.(*kotsv1beta1.Config)
+		}
+	}
+
+	if config == nil || configValuesIndex == -1 {
+		return nil
+	}
+
+	obj, _, err := decode(files[configValuesIndex].Content, nil, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to decode config values")
+	}
+	configValues, ok := obj.(*kotsv1beta1.ConfigValues)
+	if !ok {
+		return errors.New("config values file is not a configvalues object")
+	}
+
+	encryptedValues := configValues.DeepCopy()
+	if err := kotsconfig.EncryptConfigValues(config.Spec.Groups, encryptedValues, encryptionKey); err != nil {
+		return errors.Wrap(err, "failed to encrypt config values")
+	}
+	if reflect.DeepEqual(configValues, encryptedValues) {
+		return nil
+	}
+
+	files[configValuesIndex].Content = mustMarshalConfigValues(encryptedValues)
+
+	return nil
+}
+
 func getEncryptionKey(previousInstallationContent []byte) (string, error) {
 	if previousInstallationContent == nil {
 		key := make([]byte, 24) // 192 bit
@@ -190,6 +359,203 @@ func getEncryptionKey(previousInstallationContent []byte) (string, error) {
 	return installation.Spec.EncryptionKey, nil
 }
 
+// getGeneratedValues returns the values that templates generated for the previous
+// installation, or empty values if there was none
+func getGeneratedValues(previousInstallationContent []byte) (*kotsv1beta1.GeneratedValues, error) {
+	if previousInstallationContent == nil {
+		return &kotsv1beta1.GeneratedValues{}, nil
+	}
+
+	kotsscheme.AddToScheme(scheme.Scheme)
+	decode := scheme.Codecs.UniversalDeserializer().Decode
+
+	prevObj, _, err := decode(previousInstallationContent, nil, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode previous installation")
+	}
+	installation := prevObj.(*kotsv1beta1.Installation)
+
+	if installation.Spec.GeneratedValues == nil {
+		return &kotsv1beta1.GeneratedValues{}, nil
+	}
+
+	generatedValues := installation.Spec.GeneratedValues
+	if err := decryptGeneratedValues(generatedValues, installation.Spec.EncryptionKey); err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt generated values")
+	}
+
+	return generatedValues, nil
+}
+
+// encryptGeneratedValues returns a copy of the generated values with the private keys and
+// random strings encrypted, so that they are not stored in plaintext in the installation
+func encryptGeneratedValues(generatedValues *kotsv1beta1.GeneratedValues, encryptionKey string) (*kotsv1beta1.GeneratedValues, error) {
+	encrypted := generatedValues.DeepCopy()
+
+	for name, certificate := range encrypted.Certificates {
+		key, err := encryptGeneratedValue(encryptionKey, certificate.Key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to encrypt key of certificate %s", name)
+		}
+		certificate.Key = key
+		encrypted.Certificates[name] = certificate
+	}
+
+	for name, keyPair := range encrypted.KeyPairs {
+		privateKey, err := encryptGeneratedValue(encryptionKey, keyPair.PrivateKey)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to encrypt private key of key pair %s", name)
+		}
+		keyPair.PrivateKey = privateKey
+		encrypted.KeyPairs[name] = keyPair
+	}
+
+	for name, randomString := range encrypted.RandomStrings {
+		value, err := encryptGeneratedValue(encryptionKey, randomString)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to encrypt random string %s", name)
+		}
+		encrypted.RandomStrings[name] = value
+	}
+
+	return encrypted, nil
+}
+
+// decryptGeneratedValues decrypts the private keys and random strings. Values that are not encrypted, from
+// installations that were written before they were encrypted, are kept as they are
+func decryptGeneratedValues(generatedValues *kotsv1beta1.GeneratedValues, encryptionKey string) error {
+	for name, certificate := range generatedValues.Certificates {
+		key, err := crypto.Decrypt(encryptionKey, certificate.Key)
+		if err != nil {
+			return errors.Wrapf(err, "failed to decrypt key of certificate %s", name)
+		}
+		certificate.Key = key
+		generatedValues.Certificates[name] = certificate
+	}
+
+	for name, keyPair := range generatedValues.KeyPairs {
+		privateKey, err := crypto.Decrypt(encryptionKey, keyPair.PrivateKey)
+		if err != nil {
+			return errors.Wrapf(err, "failed to decrypt private key of key pair %s", name)
+		}
+		keyPair.PrivateKey = privateKey
+		generatedValues.KeyPairs[name] = keyPair
+	}
+
+	for name, randomString := range generatedValues.RandomStrings {
+		value, err := crypto.Decrypt(encryptionKey, randomString)
+		if err != nil {
+			return errors.Wrapf(err, "failed to decrypt random string %s", name)
+		}
+		generatedValues.RandomStrings[name] = value
+	}
+
+	return nil
+}
+
+func encryptGeneratedValue(encryptionKey string, value string) (string, error) {
+	if value == "" || crypto.IsEncrypted(value) {
+		return value, nil
+	}
+
+	return crypto.Encrypt(encryptionKey, value)
+}
+
+// removeRandomStringConfigValues removes the config values that are still the random
+// strings that their item's templates generated, so that new ones are generated for them.
+// Values that were changed are kept
+func removeRandomStringConfigValues(configValuesContent []byte, randomStrings map[string]string, encryptionKey string) ([]byte, error) {
+	kotsscheme.AddToScheme(scheme.Scheme)
+	decode := scheme.Codecs.UniversalDeserializer().Decode
+
+	obj, _, err := decode(configValuesContent, nil, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode config values")
+	}
+	configValues, ok := obj.(*kotsv1beta1.ConfigValues)
+	if !ok {
+		return nil, errors.New("config values file is not a configvalues object")
+	}
+
+	isGenerated := func(itemName string, value string) (bool, error) {
+		decrypted, err := crypto.Decrypt(encryptionKey, value)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to decrypt value of config item %s", itemName)
+		}
+
+		prefix := fmt.Sprintf("config/%s/", itemName)
+		for key, randomString := range randomStrings {
+			if strings.HasPrefix(key, prefix) && randomString == decrypted {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	}
+
+	for itemName, value := range configValues.Spec.Values {
+		generated, err := isGenerated(itemName, value)
+		if err != nil {
+			return nil, err
+		}
+		if generated {
+			delete(configValues.Spec.Values, itemName)
+		}
+	}
+
+	for itemName, values := range configValues.Spec.MultiValues {
+		generated := len(values) > 0
+		for _, value := range values {
+			isValueGenerated, err := isGenerated(itemName, value)
+			if err != nil {
+				return nil, err
+			}
+			generated = generated && isValueGenerated
+		}
+		if generated {
+			delete(configValues.Spec.MultiValues, itemName)
+		}
+	}
+
+	return mustMarshalConfigValues(configValues), nil
+}
+
+// mergeGeneratedValues adds the values that were generated when the upstream was fetched
+// to the previous values. The previous values are kept when both have a value
+func mergeGeneratedValues(previous *kotsv1beta1.GeneratedValues, fetched *kotsv1beta1.GeneratedValues) *kotsv1beta1.GeneratedValues {
+	if fetched == nil {
+		return previous
+	}
+
+	merged := previous.DeepCopy()
+	for name, certificate := range fetched.Certificates {
+		if _, ok := merged.Certificates[name]; !ok {
+			if merged.Certificates == nil {
+				merged.Certificates = map[string]kotsv1beta1.GeneratedCertificate{}
+			}
+			merged.Certificates[name] = certificate
+		}
+	}
+	for name, keyPair := range fetched.KeyPairs {
+		if _, ok := merged.KeyPairs[name]; !ok {
+			if merged.KeyPairs == nil {
+				merged.KeyPairs = map[string]kotsv1beta1.GeneratedKeyPair{}
+			}
+			merged.KeyPairs[name] = keyPair
+		}
+	}
+	for name, randomString := range fetched.RandomStrings {
+		if _, ok := merged.RandomStrings[name]; !ok {
+			if merged.RandomStrings == nil {
+				merged.RandomStrings = map[string]string{}
+			}
+			merged.RandomStrings[name] = randomString
+		}
+	}
+
+	return merged
+}
+
 func mergeValues(previousValues []byte, applicationDeliveredValues []byte) ([]byte, error) {
 	kotsscheme.AddToScheme(scheme.Scheme)
 	decode := scheme.Codecs.UniversalDeserializer().Decode
@@ -206,6 +572,9 @@ func mergeValues(previousValues []byte, applicationDeliveredValues []byte) ([]by
 	}
 	applicationValues := applicationValuesObj.(*kotsv1beta1.ConfigValues)
 
+	if prevValues.Spec.Values == nil {
+		prevValues.Spec.Values = map[string]string{}
+	}
 	for name, value := range applicationValues.Spec.Values {
 		_, ok := prevValues.Spec.Values[name]
 		if !ok {
@@ -213,6 +582,16 @@ func mergeValues(previousValues []byte, applicationDeliveredValues []byte) ([]by
 		}
 	}
 
+	for name, value := range applicationValues.Spec.MultiValues {
+		if _, ok := prevValues.Spec.MultiValues[name]; ok {
+			continue
+		}
+		if prevValues.Spec.MultiValues == nil {
+			prevValues.Spec.MultiValues = map[string][]string{}
+		}
+		prevValues.Spec.MultiValues[name] = value
+	}
+
 	s := json.NewYAMLSerializer(json.DefaultMetaFactory, scheme.Scheme, scheme.Scheme)
 
 	var b bytes.Buffer
@@ -0,0 +1,148 @@
+package upstream
+
+import (
+	"testing"
+
+	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
+	"github.com/replicatedhq/kots/pkg/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_getGeneratedValues(t *testing.T) {
+	tests := []struct {
+		name                        string
+		previousInstallationContent []byte
+		expected                    *kotsv1beta1.GeneratedValues
+	}{
+		{
+			name:                        "no previous installation",
+			previousInstallationContent: nil,
+			expected:                    &kotsv1beta1.GeneratedValues{},
+		},
+		{
+			name: "previous installation without generated values",
+			previousInstallationContent: []byte(`apiVersion: kots.io/v1beta1
+kind: Installation
+metadata:
+  name: app
+spec:
+  encryptionKey: key
+`),
+			expected: &kotsv1beta1.GeneratedValues{},
+		},
+		{
+			name: "previous installation with generated values",
+			previousInstallationContent: []byte(`apiVersion: kots.io/v1beta1
+kind: Installation
+metadata:
+  name: app
+spec:
+  encryptionKey: key
+  generatedValues:
+    certificates:
+      my-ca:
+        cert: ca-cert
+        key: ca-key
+    keyPairs:
+      signing:
+        publicKey: public
+        privateKey: private
+`),
+			expected: &kotsv1beta1.GeneratedValues{
+				Certificates: map[string]kotsv1beta1.GeneratedCertificate{
+					"my-ca": {Cert: "ca-cert", Key: "ca-key"},
+				},
+				KeyPairs: map[string]kotsv1beta1.GeneratedKeyPair{
+					"signing": {PublicKey: "public", PrivateKey: "private"},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := getGeneratedValues(test.previousInstallationContent)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func Test_mergeGeneratedValues(t *testing.T) {
+	previous := &kotsv1beta1.GeneratedValues{
+		Certificates: map[string]kotsv1beta1.GeneratedCertificate{
+			"my-ca": {Cert: "ca-cert", Key: "ca-key"},
+		},
+		RandomStrings: map[string]string{
+			"config/password/value/0": "previous",
+		},
+	}
+	fetched := &kotsv1beta1.GeneratedValues{
+		RandomStrings: map[string]string{
+			"config/password/value/0": "fetched",
+			"config/token/value/0":    "new",
+		},
+	}
+
+	assert.Equal(t, &kotsv1beta1.GeneratedValues{
+		Certificates: map[string]kotsv1beta1.GeneratedCertificate{
+			"my-ca": {Cert: "ca-cert", Key: "ca-key"},
+		},
+		RandomStrings: map[string]string{
+			"config/password/value/0": "previous",
+			"config/token/value/0":    "new",
+		},
+	}, mergeGeneratedValues(previous, fetched))
+	assert.Equal(t, "previous", previous.RandomStrings["config/password/value/0"])
+	assert.Equal(t, previous, mergeGeneratedValues(previous, nil))
+}
+
+func Test_encryptGeneratedValues(t *testing.T) {
+	req := require.New(t)
+
+	encryptionKey := "tUg2e934J7ethZNX2WDaiUNd0E1Z2iJ3SyQEt81zzxIiM48K"
+	generatedValues := &kotsv1beta1.GeneratedValues{
+		Certificates: map[string]kotsv1beta1.GeneratedCertificate{
+			"my-ca": {Cert: "ca-cert", Key: "ca-key"},
+		},
+		KeyPairs: map[string]kotsv1beta1.GeneratedKeyPair{
+			"signing": {PublicKey: "public", PrivateKey: "signing-private-key"},
+		},
+		RandomStrings: map[string]string{
+			"config/password/value/0": "random-password",
+		},
+	}
+
+	encrypted, err := encryptGeneratedValues(generatedValues, encryptionKey)
+	req.NoError(err)
+	assert.Equal(t, "ca-cert", encrypted.Certificates["my-ca"].Cert)
+	assert.True(t, crypto.IsEncrypted(encrypted.Certificates["my-ca"].Key))
+	assert.Equal(t, "public", encrypted.KeyPairs["signing"].PublicKey)
+	assert.True(t, crypto.IsEncrypted(encrypted.KeyPairs["signing"].PrivateKey))
+	assert.True(t, crypto.IsEncrypted(encrypted.RandomStrings["config/password/value/0"]))
+	assert.Equal(t, "ca-key", generatedValues.Certificates["my-ca"].Key)
+
+	installation := &kotsv1beta1.Installation{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "kots.io/v1beta1",
+			Kind:       "Installation",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "app",
+		},
+		Spec: kotsv1beta1.InstallationSpec{
+			EncryptionKey:   encryptionKey,
+			GeneratedValues: encrypted,
+		},
+	}
+	content := mustMarshalInstallation(installation)
+	assert.NotContains(t, string(content), "ca-key")
+	assert.NotContains(t, string(content), "signing-private-key")
+	assert.NotContains(t, string(content), "random-password")
+
+	actual, err := getGeneratedValues(content)
+	req.NoError(err)
+	assert.Equal(t, generatedValues, actual)
+}
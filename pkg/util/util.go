@@ -3,15 +3,26 @@ package util
 import (
 	"bytes"
 	"net/url"
+	"os"
 )
 
 func IsURL(str string) bool {
-	_, err := url.ParseRequestURI(str)
+	u, err := url.ParseRequestURI(str)
 	if err != nil {
 		return false
 	}
 
-	return true
+	// absolute paths are valid request uris, but they are not urls
+	return u.Scheme != ""
+}
+
+// HomeDir returns the home directory of the user, from HOME or USERPROFILE. The cli and
+// the files it keeps in the home directory must use the same one
+func HomeDir() string {
+	if h := os.Getenv("HOME"); h != "" {
+		return h
+	}
+	return os.Getenv("USERPROFILE")
 }
 
 func CommonSlicePrefix(first []string, second []string) []string {
@@ -32,6 +43,30 @@ func CommonSlicePrefix(first []string, second []string) []string {
 	return common
 }
 
+// SplitYAML splits a multi document yaml stream on the "---" separator lines. Windows line
+// endings are converted, and empty documents are not returned
+func SplitYAML(content []byte) [][]byte {
+	content = bytes.Replace(content, []byte("\r\n"), []byte("\n"), -1)
+
+	docs := [][]byte{}
+	doc := []byte{}
+	for _, line := range bytes.SplitAfter(content, []byte("\n")) {
+		if string(bytes.TrimRight(line, " \t\n")) == "---" {
+			if len(bytes.TrimSpace(doc)) > 0 {
+				docs = append(docs, doc)
+			}
+			doc = []byte{}
+			continue
+		}
+		doc = append(doc, line...)
+	}
+	if len(bytes.TrimSpace(doc)) > 0 {
+		docs = append(docs, doc)
+	}
+
+	return docs
+}
+
 func SplitStringOnLen(str string, maxLength int) ([]string, error) {
 	if maxLength >= len(str) {
 		return []string{str}, nil
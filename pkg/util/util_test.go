@@ -80,3 +80,77 @@ func Test_SplitStringOnLen(t *testing.T) {
 		})
 	}
 }
+
+func Test_IsURL(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected bool
+	}{
+		{
+			in:       "helm://stable/mysql",
+			expected: true,
+		},
+		{
+			in:       "https://example.com/app.yaml",
+			expected: true,
+		},
+		{
+			in:       "/tmp/manifests",
+			expected: false,
+		},
+		{
+			in:       "./manifests",
+			expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			assert.Equal(t, test.expected, IsURL(test.in))
+		})
+	}
+}
+
+func Test_SplitYAML(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected []string
+	}{
+		{
+			name:     "single",
+			content:  "kind: A\n",
+			expected: []string{"kind: A\n"},
+		},
+		{
+			name:     "multi",
+			content:  "kind: A\n---\nkind: B",
+			expected: []string{"kind: A\n", "kind: B"},
+		},
+		{
+			name:     "leading and trailing separators",
+			content:  "---\nkind: A\n---\n\n---\nkind: B\n---\n",
+			expected: []string{"kind: A\n", "kind: B\n"},
+		},
+		{
+			name:     "windows line endings",
+			content:  "kind: A\r\n---\r\nkind: B\r\n",
+			expected: []string{"kind: A\n", "kind: B\n"},
+		},
+		{
+			name:     "separator in a value",
+			content:  "kind: A\ndata: |\n  ---\n",
+			expected: []string{"kind: A\ndata: |\n  ---\n"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := []string{}
+			for _, doc := range SplitYAML([]byte(test.content)) {
+				actual = append(actual, string(doc))
+			}
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}